@@ -0,0 +1,49 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import "sync"
+
+// canceler signals the goroutines of a stream pipeline that no more elements
+// are needed, so that they terminate without waiting for requests which will
+// never come. It is shared by the stages of a pipeline, and canceling it also
+// cancels the cancelers of the pipelines which the pipeline consumes, such as
+// the streams concatenated by Concat, up to their sources.
+type canceler struct {
+	done     chan struct{} // closed when canceled
+	once     sync.Once
+	upstream []*canceler
+}
+
+// newCanceler returns a canceler which also cancels upstream when canceled.
+// A nil upstream canceler is ignored.
+func newCanceler(upstream ...*canceler) *canceler {
+	return &canceler{
+		done:     make(chan struct{}),
+		upstream: upstream,
+	}
+}
+
+// cancel cancels c and its upstream cancelers. cancel may be called more
+// than once, and does nothing if c is nil.
+func (c *canceler) cancel() {
+	if c == nil {
+		return
+	}
+
+	c.once.Do(func() {
+		close(c.done)
+		for _, u := range c.upstream {
+			u.cancel()
+		}
+	})
+}
+
+// canceled returns a channel which is closed when c is canceled. If c is
+// nil, the returned channel is never closed.
+func (c *canceler) canceled() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	return c.done
+}
@@ -1,16 +1,70 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import "github.com/YoshikiShibata/gostream/function"
 
+// Characteristics is a set of properties of a Collector, which can be used
+// to optimize CollectByCollector.
+type Characteristics int
+
+const (
+	// Concurrent indicates that the accumulator can be called concurrently
+	// with the same result container, so that a parallel stream can be
+	// collected into a single shared container. If a Concurrent collector
+	// is not also Unordered, it is evaluated concurrently only for an
+	// unordered stream.
+	Concurrent Characteristics = 1 << iota
+
+	// Unordered indicates that the result of the collection does not depend
+	// on the encounter order of the input elements.
+	Unordered
+
+	// IdentityFinish indicates that the finisher is the identity function
+	// and can be elided. If set, the intermediate accumulation type A must
+	// be the same as the final result type R.
+	IdentityFinish
+)
+
+// Has reports whether c has all of the characteristics of flags.
+func (c Characteristics) Has(flags Characteristics) bool {
+	return c&flags == flags
+}
+
 // Collector is a mutable reduction operation that accumulates input elements
 // into a mutable result container.
 type Collector[T, A, R any] struct {
-	supplier    function.Supplier[A]
-	accumulator function.BiConsumer[A, T]
-	combiner    function.BinaryOperator[A]
-	finisher    function.Function[A, R]
+	supplier        function.Supplier[A]
+	accumulator     function.BiConsumer[A, T]
+	combiner        function.BinaryOperator[A]
+	finisher        function.Function[A, R]
+	characteristics Characteristics
+}
+
+// NewCollector returns a new Collector described by the given supplier,
+// accumulator, combiner and finisher functions, and characteristics.
+func NewCollector[T, A, R any](
+	supplier function.Supplier[A],
+	accumulator function.BiConsumer[A, T],
+	combiner function.BinaryOperator[A],
+	finisher function.Function[A, R],
+	characteristics ...Characteristics,
+) *Collector[T, A, R] {
+	if supplier == nil || accumulator == nil || combiner == nil ||
+		finisher == nil {
+		panic("supplier, accumulator, combiner and finisher must not be nil")
+	}
+
+	c := &Collector[T, A, R]{
+		supplier:    supplier,
+		accumulator: accumulator,
+		combiner:    combiner,
+		finisher:    finisher,
+	}
+	for _, ch := range characteristics {
+		c.characteristics |= ch
+	}
+	return c
 }
 
 // Supplier is a function that creates and returns a new mutable result
@@ -37,3 +91,8 @@ func (c *Collector[T, A, R]) Combiner() function.BinaryOperator[A] {
 func (c *Collector[T, A, R]) Finisher() function.Function[A, R] {
 	return c.finisher
 }
+
+// Characteristics returns the set of characteristics of the collector.
+func (c *Collector[T, A, R]) Characteristics() Characteristics {
+	return c.characteristics
+}
@@ -1,11 +1,17 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
+	"cmp"
+	"container/heap"
 	"fmt"
+	"hash/maphash"
+	"maps"
 	"math"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/YoshikiShibata/gostream/function"
 )
@@ -50,28 +56,59 @@ func ToSetCollector[T comparable]() *Collector[T, map[T]bool, map[T]bool] {
 		finisher: func(t map[T]bool) map[T]bool {
 			return t
 		},
+		characteristics: Unordered | IdentityFinish,
 	}
 }
 
+// Joiner is the intermediate accumulation type of JoiningCollector and
+// JoiningCollector3, which accumulates strings separated by sep.
+type Joiner struct {
+	sep     string
+	builder strings.Builder
+	empty   bool
+}
+
+func (j *Joiner) add(s string) {
+	if !j.empty {
+		j.builder.WriteString(j.sep)
+	}
+	j.builder.WriteString(s)
+	j.empty = false
+}
+
 // JoiningCollector returns a Collector that concatenates the input elements
 // into a string, in encounter order.
 func JoiningCollector(
 	sep string,
-) *Collector[string, *[]string, string] {
-	return &Collector[string, *[]string, string]{
-		supplier: func() *[]string {
-			var s []string
-			return &s
+) *Collector[string, *Joiner, string] {
+	return JoiningCollector3(sep, "", "")
+}
+
+// JoiningCollector3 returns a Collector that concatenates the input elements,
+// separated by sep, with prefix and suffix into a string, in encounter order.
+func JoiningCollector3(
+	sep, prefix, suffix string,
+) *Collector[string, *Joiner, string] {
+	return &Collector[string, *Joiner, string]{
+		supplier: func() *Joiner {
+			return &Joiner{sep: sep, empty: true}
 		},
-		accumulator: func(b *[]string, s string) {
-			*b = append(*b, s)
+		accumulator: func(j *Joiner, s string) {
+			j.add(s)
 		},
-		combiner: func(left, right *[]string) *[]string {
-			*left = append(*left, *right...)
+		combiner: func(left, right *Joiner) *Joiner {
+			if !right.empty {
+				left.add(right.builder.String())
+			}
 			return left
 		},
-		finisher: func(b *[]string) string {
-			return strings.Join(*b, sep)
+		finisher: func(j *Joiner) string {
+			var b strings.Builder
+			b.Grow(len(prefix) + j.builder.Len() + len(suffix))
+			b.WriteString(prefix)
+			b.WriteString(j.builder.String())
+			b.WriteString(suffix)
+			return b.String()
 		},
 	}
 }
@@ -89,8 +126,9 @@ func MappingCollector[T, U, A, R any](
 		accumulator: func(r A, t T) {
 			downstreamAccumulator(r, mapper(t))
 		},
-		combiner: downstream.Combiner(),
-		finisher: downstream.Finisher(),
+		combiner:        downstream.Combiner(),
+		finisher:        downstream.Finisher(),
+		characteristics: downstream.Characteristics(),
 	}
 }
 
@@ -111,8 +149,9 @@ func FlatMappingCollector[T, U, A, R any](
 				downstreamAccumulator(r, u)
 			})
 		},
-		combiner: downstream.Combiner(),
-		finisher: downstream.Finisher(),
+		combiner:        downstream.Combiner(),
+		finisher:        downstream.Finisher(),
+		characteristics: downstream.Characteristics(),
 	}
 }
 
@@ -132,8 +171,9 @@ func FilteringCollector[T, A, R any](
 				downstreamAccumulator(r, t)
 			}
 		},
-		combiner: downstream.Combiner(),
-		finisher: downstream.Finisher(),
+		combiner:        downstream.Combiner(),
+		finisher:        downstream.Finisher(),
+		characteristics: downstream.Characteristics(),
 	}
 }
 
@@ -193,9 +233,282 @@ func GroupingByCollector[T any, K comparable, A, D any](
 			}
 			return result
 		},
+		characteristics: downstream.Characteristics() & Unordered,
+	}
+}
+
+// GroupingBySortedCollector returns a Collector implementing a cascaded
+// "group by" operation as GroupingByCollector does, except that the result is
+// a slice of entries sorted by key.
+func GroupingBySortedCollector[T any, K cmp.Ordered, A, D any](
+	classifier function.Function[T, K],
+	downstream *Collector[T, A, D],
+) *Collector[T, map[K]A, []Entry[K, D]] {
+	grouping := GroupingByCollector(classifier, downstream)
+
+	return &Collector[T, map[K]A, []Entry[K, D]]{
+		supplier:    grouping.supplier,
+		accumulator: grouping.accumulator,
+		combiner:    grouping.combiner,
+		finisher: func(a map[K]A) []Entry[K, D] {
+			entries := make([]Entry[K, D], 0, len(a))
+			for k, v := range a {
+				entries = append(entries, Entry[K, D]{
+					Key:   k,
+					Value: downstream.Finisher()(v),
+				})
+			}
+			slices.SortFunc(entries, func(e1, e2 Entry[K, D]) int {
+				return cmp.Compare(e1.Key, e2.Key)
+			})
+			return entries
+		},
+		characteristics: grouping.characteristics,
+	}
+}
+
+// CountingByCollector returns a Collector counting input elements of type T
+// by a classifier function. The collector produces a map[K]int64 whose keys
+// are the values resulting from applying the classifier function to the
+// input elements, and whose values are the numbers of the elements mapped to
+// the keys. It is equivalent to GroupingByCollector with CountingCollector,
+// but faster.
+func CountingByCollector[T any, K comparable](
+	classifier function.Function[T, K],
+) *Collector[T, map[K]int64, map[K]int64] {
+	return &Collector[T, map[K]int64, map[K]int64]{
+		supplier: func() map[K]int64 {
+			return make(map[K]int64)
+		},
+		accumulator: func(m map[K]int64, t T) {
+			m[classifier(t)]++
+		},
+		combiner: func(m1, m2 map[K]int64) map[K]int64 {
+			for k, count := range m2 {
+				m1[k] += count
+			}
+			return m1
+		},
+		finisher: func(m map[K]int64) map[K]int64 {
+			return m
+		},
+		characteristics: Unordered | IdentityFinish,
+	}
+}
+
+// ModeCollector returns a Collector that produces the most frequent input
+// elements. If several elements are equally most frequent, all of them are
+// produced, in unspecified order. If no elements are present, the result is
+// an empty slice.
+func ModeCollector[T comparable]() *Collector[T, map[T]int64, []T] {
+	counting := CountingByCollector(Identity[T])
+
+	return &Collector[T, map[T]int64, []T]{
+		supplier:    counting.supplier,
+		accumulator: counting.accumulator,
+		combiner:    counting.combiner,
+		finisher: func(m map[T]int64) []T {
+			modes := []T{}
+			var maxCount int64
+			for t, count := range m {
+				switch {
+				case count > maxCount:
+					modes = append(modes[:0], t)
+					maxCount = count
+				case count == maxCount:
+					modes = append(modes, t)
+				}
+			}
+			return modes
+		},
+		characteristics: Unordered,
+	}
+}
+
+// DuplicatesCollector returns a Collector that produces the input elements
+// appearing more than once, as a map from each of them to its number of
+// occurrences. If no elements are duplicated, the result is an empty map.
+func DuplicatesCollector[T comparable]() *Collector[T, map[T]int64, map[T]int64] {
+	counting := CountingByCollector(Identity[T])
+
+	return &Collector[T, map[T]int64, map[T]int64]{
+		supplier:    counting.supplier,
+		accumulator: counting.accumulator,
+		combiner:    counting.combiner,
+		finisher: func(m map[T]int64) map[T]int64 {
+			maps.DeleteFunc(m, func(_ T, count int64) bool {
+				return count < 2
+			})
+			return m
+		},
+		characteristics: Unordered,
+	}
+}
+
+// ApproxDistinctCollector returns a Collector that produces the estimated
+// number of distinct input elements, using HyperLogLog with 2^precision
+// registers, where precision must be in [4, 16]. The memory used is constant
+// regardless of the number of elements, and the relative standard error of
+// the estimate is about 1.04/sqrt(2^precision): for example, about 1.6% for
+// precision 12.
+func ApproxDistinctCollector[T comparable](
+	precision int,
+) *Collector[T, *HyperLogLog, int64] {
+	if precision < 4 || precision > 16 {
+		panic(fmt.Sprintf("precision must be in [4, 16]: %v", precision))
+	}
+	hash := hasher[T](maphash.MakeSeed())
+
+	return &Collector[T, *HyperLogLog, int64]{
+		supplier: func() *HyperLogLog {
+			return newHyperLogLog(precision)
+		},
+		accumulator: func(h *HyperLogLog, t T) {
+			h.add(hash(t))
+		},
+		combiner: func(h1, h2 *HyperLogLog) *HyperLogLog {
+			h1.merge(h2)
+			return h1
+		},
+		finisher: func(h *HyperLogLog) int64 {
+			return h.estimate()
+		},
+		characteristics: Unordered,
+	}
+}
+
+// Frequencies is the intermediate accumulation type of
+// ToFrequencyRankedSliceCollector, which counts occurrences of elements,
+// recording the encounter index of the first occurrence of each element.
+type Frequencies[T comparable] struct {
+	counts map[T]*frequency
+	total  int64
+}
+
+type frequency struct {
+	count int64
+	first int64
+}
+
+func (f *Frequencies[T]) add(t T, count, first int64) {
+	if c, ok := f.counts[t]; ok {
+		c.count += count
+		return
+	}
+	f.counts[t] = &frequency{count: count, first: first}
+}
+
+// ToFrequencyRankedSliceCollector returns a Collector that produces the
+// distinct input elements sorted by descending number of occurrences, where
+// elements with the same number of occurrences are in encounter order of
+// their first occurrences. If limit is not negative, at most limit elements
+// are produced.
+func ToFrequencyRankedSliceCollector[T comparable](
+	limit int,
+) *Collector[T, *Frequencies[T], []T] {
+	return &Collector[T, *Frequencies[T], []T]{
+		supplier: func() *Frequencies[T] {
+			return &Frequencies[T]{counts: make(map[T]*frequency)}
+		},
+		accumulator: func(f *Frequencies[T], t T) {
+			f.add(t, 1, f.total)
+			f.total++
+		},
+		combiner: func(f1, f2 *Frequencies[T]) *Frequencies[T] {
+			for t, c := range f2.counts {
+				f1.add(t, c.count, f1.total+c.first)
+			}
+			f1.total += f2.total
+			return f1
+		},
+		finisher: func(f *Frequencies[T]) []T {
+			result := make([]T, 0, len(f.counts))
+			for t := range f.counts {
+				result = append(result, t)
+			}
+			slices.SortFunc(result, func(a, b T) int {
+				fa, fb := f.counts[a], f.counts[b]
+				if c := cmp.Compare(fb.count, fa.count); c != 0 {
+					return c
+				}
+				return cmp.Compare(fa.first, fb.first)
+			})
+			if limit >= 0 && limit < len(result) {
+				result = result[:limit]
+			}
+			return result
+		},
+	}
+}
+
+// HistogramCollector returns a Collector which counts the input elements
+// per bucket. The bucket function maps an element to a bucket index in the
+// range [0, numBuckets), and the result holds the count of each bucket.
+// HistogramCollector panics if the bucket function returns an index out of
+// the range.
+func HistogramCollector[T any](
+	bucket func(T) int,
+	numBuckets int,
+) *Collector[T, []int64, []int64] {
+	if numBuckets < 0 {
+		panic(fmt.Sprintf("numBuckets must not be negative: %v", numBuckets))
+	}
+
+	return &Collector[T, []int64, []int64]{
+		supplier: func() []int64 {
+			return make([]int64, numBuckets)
+		},
+		accumulator: func(counts []int64, t T) {
+			i := bucket(t)
+			if i < 0 || i >= numBuckets {
+				panic(fmt.Sprintf("bucket index out of range [%d] with %d buckets",
+					i, numBuckets))
+			}
+			counts[i]++
+		},
+		combiner: func(counts1, counts2 []int64) []int64 {
+			for i, count := range counts2 {
+				counts1[i] += count
+			}
+			return counts1
+		},
+		finisher: func(counts []int64) []int64 {
+			return counts
+		},
+		characteristics: Unordered | IdentityFinish,
 	}
 }
 
+// HistogramByBoundariesCollector returns a Collector which counts the input
+// elements per bucket delimited by boundaries, which must be sorted in
+// increasing order. The result holds len(boundaries)+1 counts: the first
+// count is of elements less than boundaries[0], the i-th count is of elements
+// in [boundaries[i-1], boundaries[i]), and the last count is of elements
+// greater than or equal to the last boundary.
+func HistogramByBoundariesCollector[T Number](
+	boundaries ...T,
+) *Collector[T, []int64, []int64] {
+	if !slices.IsSorted(boundaries) {
+		panic(fmt.Sprintf("boundaries are not sorted: %v", boundaries))
+	}
+	boundaries = slices.Clone(boundaries)
+
+	return HistogramCollector(
+		func(t T) int {
+			i, found := slices.BinarySearch(boundaries, t)
+			if found {
+				// skip equal boundaries, as each bucket is closed on
+				// its lower boundary.
+				for i < len(boundaries) && boundaries[i] == t {
+					i++
+				}
+			}
+			return i
+		},
+		len(boundaries)+1,
+	)
+}
+
 // PartitioningByToSliceCollector returns a Collector which partitions the
 // input elements according to a Predicated, and organizes them into
 // a map[bool][]T.
@@ -242,9 +555,54 @@ func PartitioningByCollector[T, D, A any](
 			result[true] = downstream.Finisher()((*partition)[1])
 			return result
 		},
+		characteristics: downstream.Characteristics() & Unordered,
+	}
+}
+
+// TeeingCollector returns a Collector that is a composite of two downstream
+// collectors. Every element is processed by both downstream collectors, and
+// their results are merged using the merger function into the final result.
+func TeeingCollector[T, A1, R1, A2, R2, R any](
+	downstream1 *Collector[T, A1, R1],
+	downstream2 *Collector[T, A2, R2],
+	merger func(r1 R1, r2 R2) R,
+) *Collector[T, *Pair[A1, A2], R] {
+	accumulator1 := downstream1.Accumulator()
+	accumulator2 := downstream2.Accumulator()
+
+	return &Collector[T, *Pair[A1, A2], R]{
+		supplier: func() *Pair[A1, A2] {
+			p := PairOf(downstream1.Supplier()(), downstream2.Supplier()())
+			return &p
+		},
+		accumulator: func(p *Pair[A1, A2], t T) {
+			accumulator1(p.First, t)
+			accumulator2(p.Second, t)
+		},
+		combiner: func(p1, p2 *Pair[A1, A2]) *Pair[A1, A2] {
+			p1.First = downstream1.Combiner()(p1.First, p2.First)
+			p1.Second = downstream2.Combiner()(p1.Second, p2.Second)
+			return p1
+		},
+		finisher: func(p *Pair[A1, A2]) R {
+			return merger(
+				downstream1.Finisher()(p.First),
+				downstream2.Finisher()(p.Second))
+		},
+		characteristics: downstream1.Characteristics() &
+			downstream2.Characteristics() & (Concurrent | Unordered),
 	}
 }
 
+// PairCollector returns a Collector that is a composite of two downstream
+// collectors, whose results are produced as a Pair.
+func PairCollector[T, A1, R1, A2, R2 any](
+	downstream1 *Collector[T, A1, R1],
+	downstream2 *Collector[T, A2, R2],
+) *Collector[T, *Pair[A1, A2], Pair[R1, R2]] {
+	return TeeingCollector(downstream1, downstream2, PairOf[R1, R2])
+}
+
 // ToUniqueKeysMapCollector returns a Collector that accumulate elements into
 // a map[K]U whose keys and values are the result of applying the provided
 // mapping functions to the input elements.
@@ -280,6 +638,7 @@ func ToUniqueKeysMapCollector[T any, K comparable, U any](
 		finisher: func(m map[K]U) map[K]U {
 			return m
 		},
+		characteristics: Unordered | IdentityFinish,
 	}
 }
 
@@ -295,11 +654,31 @@ func ToMapCollector[T any, K comparable, U any](
 	valueMapper function.Function[T, U],
 	mergeFunction function.BinaryOperator[U],
 ) *Collector[T, map[K]U, map[K]U] {
-	return &Collector[T, map[K]U, map[K]U]{
-		supplier: func() map[K]U {
+	return ToMapWithSupplierCollector(
+		keyMapper,
+		valueMapper,
+		mergeFunction,
+		func() map[K]U {
 			return make(map[K]U)
 		},
-		accumulator: func(m map[K]U, t T) {
+	)
+}
+
+// ToMapWithSupplierCollector returns a Collector that accumulates elements
+// into a map M, created by the provided supplier, as ToMapCollector does.
+//
+// The supplier may return a pre-sized map or a map of a named map type. As
+// the supplier is called for each worker of a parallel stream, it must
+// return a new map for each call, and the result is one of them.
+func ToMapWithSupplierCollector[T any, K comparable, U any, M ~map[K]U](
+	keyMapper function.Function[T, K],
+	valueMapper function.Function[T, U],
+	mergeFunction function.BinaryOperator[U],
+	mapSupplier function.Supplier[M],
+) *Collector[T, M, M] {
+	return &Collector[T, M, M]{
+		supplier: mapSupplier,
+		accumulator: func(m M, t T) {
 			key := keyMapper(t)
 			value := valueMapper(t)
 
@@ -309,7 +688,7 @@ func ToMapCollector[T any, K comparable, U any](
 			}
 			m[key] = value
 		},
-		combiner: func(m1, m2 map[K]U) map[K]U {
+		combiner: func(m1, m2 M) M {
 			for key, v2 := range m2 {
 				var value U
 
@@ -323,9 +702,81 @@ func ToMapCollector[T any, K comparable, U any](
 			}
 			return m1
 		},
-		finisher: func(m map[K]U) map[K]U {
+		finisher: func(m M) M {
 			return m
 		},
+		characteristics: IdentityFinish,
+	}
+}
+
+// ToOrderedMapCollector returns a Collector that accumulates elements into an
+// OrderedMap, whose keys are in encounter order of their first elements, as
+// ToMapCollector does.
+func ToOrderedMapCollector[T any, K comparable, U any](
+	keyMapper function.Function[T, K],
+	valueMapper function.Function[T, U],
+	mergeFunction function.BinaryOperator[U],
+) *Collector[T, *OrderedMap[K, U], *OrderedMap[K, U]] {
+	accept := func(m *OrderedMap[K, U], key K, value U) {
+		if v, ok := m.Get(key); ok {
+			value = mergeFunction(v, value)
+		}
+		m.put(key, value)
+	}
+
+	return &Collector[T, *OrderedMap[K, U], *OrderedMap[K, U]]{
+		supplier: newOrderedMap[K, U],
+		accumulator: func(m *OrderedMap[K, U], t T) {
+			accept(m, keyMapper(t), valueMapper(t))
+		},
+		combiner: func(m1, m2 *OrderedMap[K, U]) *OrderedMap[K, U] {
+			for _, e := range m2.entries {
+				accept(m1, e.Key, e.Value)
+			}
+			return m1
+		},
+		finisher: func(m *OrderedMap[K, U]) *OrderedMap[K, U] {
+			return m
+		},
+		characteristics: IdentityFinish,
+	}
+}
+
+// ToSyncMapCollector returns a Collector that accumulates elements into a
+// *sync.Map whose keys and values are the result of applying the provided
+// mapping functions to the input elements. The collector is Concurrent, so
+// all workers of a parallel stream accumulate elements into a single shared
+// *sync.Map without merging maps.
+//
+// If the mapped keys contains duplicates, this function panics.
+func ToSyncMapCollector[T any, K comparable, U any](
+	keyMapper function.Function[T, K],
+	valueMapper function.Function[T, U],
+) *Collector[T, *sync.Map, *sync.Map] {
+	store := func(m *sync.Map, key, value any) {
+		if _, loaded := m.LoadOrStore(key, value); loaded {
+			panic(fmt.Sprintf("duplicated key: %v", key))
+		}
+	}
+
+	return &Collector[T, *sync.Map, *sync.Map]{
+		supplier: func() *sync.Map {
+			return new(sync.Map)
+		},
+		accumulator: func(m *sync.Map, t T) {
+			store(m, keyMapper(t), valueMapper(t))
+		},
+		combiner: func(m1, m2 *sync.Map) *sync.Map {
+			m2.Range(func(key, value any) bool {
+				store(m1, key, value)
+				return true
+			})
+			return m1
+		},
+		finisher: func(m *sync.Map) *sync.Map {
+			return m
+		},
+		characteristics: Concurrent | Unordered | IdentityFinish,
 	}
 }
 
@@ -338,17 +789,42 @@ func SummarizingCollector[T any, R Number](
 	return &Collector[T, *SummaryStatistics[R], *SummaryStatistics[R]]{
 		supplier: NewSummaryStatistics[R],
 		accumulator: func(i *SummaryStatistics[R], t T) {
-			i.accept(mapper(t))
+			i.Accept(mapper(t))
 		},
 		combiner: func(l *SummaryStatistics[R],
 			r *SummaryStatistics[R],
 		) *SummaryStatistics[R] {
-			l.combine(r)
+			l.Combine(r)
 			return l
 		},
 		finisher: func(i *SummaryStatistics[R]) *SummaryStatistics[R] {
 			return i
 		},
+		characteristics: Unordered | IdentityFinish,
+	}
+}
+
+// SummarizingFloat64Collector returns a Collector which applies an
+// float64-producing mapping function to each input element, and returns
+// summary statistics for the resulting values.
+func SummarizingFloat64Collector[T any](
+	mapper function.Function[T, float64],
+) *Collector[T, *Float64SummaryStatistics, *Float64SummaryStatistics] {
+	return &Collector[T, *Float64SummaryStatistics, *Float64SummaryStatistics]{
+		supplier: NewFloat64SummaryStatistics,
+		accumulator: func(f *Float64SummaryStatistics, t T) {
+			f.Accept(mapper(t))
+		},
+		combiner: func(l *Float64SummaryStatistics,
+			r *Float64SummaryStatistics,
+		) *Float64SummaryStatistics {
+			l.Combine(r)
+			return l
+		},
+		finisher: func(f *Float64SummaryStatistics) *Float64SummaryStatistics {
+			return f
+		},
+		characteristics: Unordered | IdentityFinish,
 	}
 }
 
@@ -372,6 +848,7 @@ func SummingCollector[T any, R Number](
 		finisher: func(a *R) R {
 			return *a
 		},
+		characteristics: Unordered,
 	}
 }
 
@@ -409,6 +886,9 @@ func ReducingCollector[T any](
 	}
 }
 
+// ReducingToOptionalCollector returns a Collector which performs a reduction
+// of its input elements under a specified BinaryOperator. The result is
+// described as an *Optional[T], which is empty if no elements are present.
 func ReducingToOptionalCollector[T any](
 	op function.BinaryOperator[T],
 ) *Collector[T, *Optional[T], *Optional[T]] {
@@ -434,7 +914,8 @@ func ReducingToOptionalCollector[T any](
 			}
 			return a
 		},
-		finisher: Identity[*Optional[T]],
+		finisher:        Identity[*Optional[T]],
+		characteristics: IdentityFinish,
 	}
 }
 
@@ -470,9 +951,62 @@ func MinByCollector[T any](
 	)
 }
 
-// AveragingInt64 returns a Collector that produces the arithmetic mean of an
-// an int64-valued function applied to the input elements. If no elements are
-// present, the result is 0.
+// MinMax holds the minimal and maximal elements and the number of elements,
+// produced by MinMaxCollector.
+type MinMax[T any] struct {
+	Min   T
+	Max   T
+	Count int64
+}
+
+// MinMaxCollector returns a Collector that produces both the minimal and
+// maximal elements according to a given Less in one pass, described as an
+// *Optional[MinMax[T]]. The minimal and maximal elements are the same as
+// produced by MinByCollector and MaxByCollector respectively.
+func MinMaxCollector[T any](
+	less Less[T],
+) *Collector[T, *Optional[MinMax[T]], *Optional[MinMax[T]]] {
+	combine := func(a, b MinMax[T]) MinMax[T] {
+		result := MinMax[T]{Min: b.Min, Max: a.Max, Count: a.Count + b.Count}
+		if less(a.Min, b.Min) {
+			result.Min = a.Min
+		}
+		if less(a.Max, b.Max) {
+			result.Max = b.Max
+		}
+		return result
+	}
+
+	accept := func(o *Optional[MinMax[T]], mm MinMax[T]) {
+		if o.present {
+			o.value = combine(o.value, mm)
+		} else {
+			o.value = mm
+			o.present = true
+		}
+	}
+
+	return &Collector[T, *Optional[MinMax[T]], *Optional[MinMax[T]]]{
+		supplier: func() *Optional[MinMax[T]] {
+			return OptionalEmpty[MinMax[T]]()
+		},
+		accumulator: func(a *Optional[MinMax[T]], t T) {
+			accept(a, MinMax[T]{Min: t, Max: t, Count: 1})
+		},
+		combiner: func(a, b *Optional[MinMax[T]]) *Optional[MinMax[T]] {
+			if b.present {
+				accept(a, b.value)
+			}
+			return a
+		},
+		finisher:        Identity[*Optional[MinMax[T]]],
+		characteristics: IdentityFinish,
+	}
+}
+
+// AveragingInt64Collector returns a Collector that produces the arithmetic
+// mean of an int64-valued function applied to the input elements. If no
+// elements are present, the result is 0.
 func AveragingInt64Collector[T any](
 	mapper function.Function[T, int64],
 ) *Collector[T, *[2]int64, float64] {
@@ -495,9 +1029,13 @@ func AveragingInt64Collector[T any](
 			}
 			return float64((*a)[0]) / float64((*a)[1])
 		},
+		characteristics: Unordered,
 	}
 }
 
+// AveragingFloat64Collector returns a Collector that produces the arithmetic
+// mean of a float64-valued function applied to the input elements. The sum is
+// computed by Kahan summation. If no elements are present, the result is 0.
 func AveragingFloat64Collector[T any](
 	mapper function.Function[T, float64],
 ) *Collector[T, *[4]float64, float64] {
@@ -552,5 +1090,275 @@ func AveragingFloat64Collector[T any](
 			}
 			return computeFinalSum(a) / (*a)[2]
 		},
+		characteristics: Unordered,
+	}
+}
+
+// WeightedSums is the intermediate accumulation type of
+// WeightedAveragingCollector, which holds the sum of weighted values and the
+// sum of weights, computed by Kahan summation.
+type WeightedSums struct {
+	values  compensatedSum
+	weights compensatedSum
+}
+
+// WeightedAveragingCollector returns a Collector that produces the weighted
+// arithmetic mean of a float64-valued function applied to the input elements,
+// weighted by another float64-valued function. The sums are computed by Kahan
+// summation. If no elements are present or the sum of weights is 0, the
+// result is 0.
+func WeightedAveragingCollector[T any](
+	valueMapper function.Function[T, float64],
+	weightMapper function.Function[T, float64],
+) *Collector[T, *WeightedSums, float64] {
+	return &Collector[T, *WeightedSums, float64]{
+		supplier: func() *WeightedSums {
+			return new(WeightedSums)
+		},
+		accumulator: func(a *WeightedSums, t T) {
+			weight := weightMapper(t)
+			a.values.add(valueMapper(t) * weight)
+			a.weights.add(weight)
+		},
+		combiner: func(a, b *WeightedSums) *WeightedSums {
+			a.values.combine(&b.values)
+			a.weights.combine(&b.weights)
+			return a
+		},
+		finisher: func(a *WeightedSums) float64 {
+			weights := a.weights.value()
+			if weights == 0 {
+				return 0
+			}
+			return a.values.value() / weights
+		},
+		characteristics: Unordered,
+	}
+}
+
+// VarianceCollector returns a Collector that produces the population
+// variance of a float64-valued function applied to the input elements. The
+// variance is computed by Welford's online algorithm. If no elements are
+// present, the result is 0.
+func VarianceCollector[T any](
+	mapper function.Function[T, float64],
+) *Collector[T, *Welford, float64] {
+	return &Collector[T, *Welford, float64]{
+		supplier: func() *Welford {
+			return new(Welford)
+		},
+		accumulator: func(w *Welford, t T) {
+			w.add(mapper(t))
+		},
+		combiner: func(w1, w2 *Welford) *Welford {
+			w1.combine(w2)
+			return w1
+		},
+		finisher: func(w *Welford) float64 {
+			return w.variance()
+		},
+		characteristics: Unordered,
+	}
+}
+
+// StdDevCollector returns a Collector that produces the population standard
+// deviation of a float64-valued function applied to the input elements. If
+// no elements are present, the result is 0.
+func StdDevCollector[T any](
+	mapper function.Function[T, float64],
+) *Collector[T, *Welford, float64] {
+	c := VarianceCollector(mapper)
+	c.finisher = func(w *Welford) float64 {
+		return math.Sqrt(w.variance())
+	}
+	return c
+}
+
+// MomentsCollector returns a Collector that produces the Moments, which are
+// the mean, variance, skewness and kurtosis, of a float64-valued function
+// applied to the input elements, computed in a single numerically stable
+// pass.
+func MomentsCollector[T any](
+	mapper function.Function[T, float64],
+) *Collector[T, *MomentsAccumulator, Moments] {
+	return &Collector[T, *MomentsAccumulator, Moments]{
+		supplier: func() *MomentsAccumulator {
+			return new(MomentsAccumulator)
+		},
+		accumulator: func(m *MomentsAccumulator, t T) {
+			m.add(mapper(t))
+		},
+		combiner: func(m1, m2 *MomentsAccumulator) *MomentsAccumulator {
+			m1.combine(m2)
+			return m1
+		},
+		finisher: func(m *MomentsAccumulator) Moments {
+			return m.toMoments()
+		},
+		characteristics: Unordered,
+	}
+}
+
+// minHeap is a min-heap according to less.
+type minHeap[T any] struct {
+	less  Less[T]
+	items []T
+}
+
+func (h *minHeap[T]) Len() int           { return len(h.items) }
+func (h *minHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *minHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *minHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+
+func (h *minHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// BoundedHeap is the intermediate accumulation type of TopNCollector, which
+// holds at most n of the largest elements added to it.
+type BoundedHeap[T any] struct {
+	n    int
+	heap minHeap[T]
+}
+
+// add adds t if fewer than n elements are held, or replaces the smallest
+// element with t if t is larger than it.
+func (h *BoundedHeap[T]) add(t T) {
+	if len(h.heap.items) < h.n {
+		heap.Push(&h.heap, t)
+		return
+	}
+	if h.n > 0 && h.heap.less(h.heap.items[0], t) {
+		h.heap.items[0] = t
+		heap.Fix(&h.heap, 0)
+	}
+}
+
+// TopNCollector returns a Collector that produces the n largest input
+// elements according to a given Less, in descending order. Only n elements
+// are held at a time, so the input elements are not sorted entirely.
+func TopNCollector[T any](
+	n int,
+	less Less[T],
+) *Collector[T, *BoundedHeap[T], []T] {
+	if n < 0 {
+		panic(fmt.Sprintf("n must not be negative: %v", n))
+	}
+
+	return &Collector[T, *BoundedHeap[T], []T]{
+		supplier: func() *BoundedHeap[T] {
+			return &BoundedHeap[T]{n: n, heap: minHeap[T]{less: less}}
+		},
+		accumulator: func(h *BoundedHeap[T], t T) {
+			h.add(t)
+		},
+		combiner: func(h1, h2 *BoundedHeap[T]) *BoundedHeap[T] {
+			for _, t := range h2.heap.items {
+				h1.add(t)
+			}
+			return h1
+		},
+		finisher: func(h *BoundedHeap[T]) []T {
+			result := slices.Clone(h.heap.items)
+			slices.SortFunc(result, func(a, b T) int {
+				switch {
+				case less(b, a):
+					return -1
+				case less(a, b):
+					return 1
+				}
+				return 0
+			})
+			return result
+		},
+	}
+}
+
+// RingBuffer is the intermediate accumulation type of LastNCollector, which
+// holds the last n elements added to it.
+type RingBuffer[T any] struct {
+	n     int
+	items []T
+	start int // index of the oldest element once items is full
+}
+
+func (r *RingBuffer[T]) add(t T) {
+	switch {
+	case len(r.items) < r.n:
+		r.items = append(r.items, t)
+	case r.n > 0:
+		r.items[r.start] = t
+		r.start = (r.start + 1) % r.n
+	}
+}
+
+// toSlice returns the elements from the oldest to the newest.
+func (r *RingBuffer[T]) toSlice() []T {
+	return append(slices.Clone(r.items[r.start:]), r.items[:r.start]...)
+}
+
+// LastNCollector returns a Collector that produces the last n input elements
+// in encounter order. Only n elements are held at a time for a sequential
+// stream.
+func LastNCollector[T any](n int) *Collector[T, *RingBuffer[T], []T] {
+	if n < 0 {
+		panic(fmt.Sprintf("n must not be negative: %v", n))
+	}
+
+	return &Collector[T, *RingBuffer[T], []T]{
+		supplier: func() *RingBuffer[T] {
+			return &RingBuffer[T]{n: n}
+		},
+		accumulator: func(r *RingBuffer[T], t T) {
+			r.add(t)
+		},
+		combiner: func(r1, r2 *RingBuffer[T]) *RingBuffer[T] {
+			for _, t := range r2.toSlice() {
+				r1.add(t)
+			}
+			return r1
+		},
+		finisher: func(r *RingBuffer[T]) []T {
+			return r.toSlice()
+		},
+	}
+}
+
+// PercentileCollector returns a Collector which estimates the values at the
+// given quantiles, each of which must be in [0, 1]: for example, 0.5, 0.95
+// and 0.99 for p50, p95 and p99. The input elements are summarized by a
+// t-digest sketch instead of being buffered, so the results are estimates,
+// which are more accurate for quantiles near 0 and 1. The result holds the
+// estimate of each quantile in the given order. If no elements are present,
+// each estimate is NaN.
+func PercentileCollector[T Number](
+	quantiles ...float64,
+) *Collector[T, *TDigest, []float64] {
+	for _, q := range quantiles {
+		if !(0 <= q && q <= 1) {
+			panic(fmt.Sprintf("quantile must be in [0, 1]: %v", q))
+		}
+	}
+	quantiles = slices.Clone(quantiles)
+
+	return &Collector[T, *TDigest, []float64]{
+		supplier: newTDigest,
+		accumulator: func(td *TDigest, t T) {
+			td.add(float64(t))
+		},
+		combiner: func(td1, td2 *TDigest) *TDigest {
+			td1.merge(td2)
+			return td1
+		},
+		finisher: func(td *TDigest) []float64 {
+			result := make([]float64, len(quantiles))
+			for i, q := range quantiles {
+				result[i] = td.quantile(q)
+			}
+			return result
+		},
+		characteristics: Unordered,
 	}
 }
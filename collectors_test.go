@@ -1,14 +1,19 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"math/rand"
 	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestCollectors_ToSliceCollector(t *testing.T) {
@@ -82,6 +87,153 @@ func TestCollectors_ToSetCollector(t *testing.T) {
 	}
 }
 
+func TestCollectors_Characteristics(t *testing.T) {
+	t.Run("Ordered", func(t *testing.T) {
+		data := Range(0, 1000).ToSlice()
+		result := CollectByCollector(Of(data...).ParallelN(4),
+			ToSliceCollector[int]())
+		if !slices.Equal(result, data) {
+			t.Errorf("result is %v, want %v", result, data)
+		}
+
+		// the elements are served through channels out of encounter order.
+		isEven := func(v int) bool { return v%2 == 0 }
+		s := Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(1000).ParallelN(8).Peek(func(int) {
+			time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+		}).Filter(isEven)
+		result = CollectByCollector(s, ToSliceCollector[int]())
+		if want := Range(0, 1000).Filter(isEven).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		for _, unordered := range [...]bool{false, true} {
+			var lock sync.Mutex
+			supplied := 0
+
+			collector := &Collector[int, *int, int]{
+				supplier: func() *int {
+					supplied++
+					return new(int)
+				},
+				accumulator: func(a *int, t int) {
+					lock.Lock()
+					defer lock.Unlock()
+					*a += t
+				},
+				combiner: func(a, b *int) *int {
+					t.Errorf("combiner is called")
+					return a
+				},
+				finisher: func(a *int) int {
+					return *a
+				},
+				characteristics: Concurrent,
+			}
+
+			s := Range(0, 100).ParallelN(4)
+			if unordered {
+				collector.characteristics |= Unordered
+			} else {
+				s = s.Unordered()
+			}
+
+			result := CollectByCollector(s, collector)
+			if result != 4950 {
+				t.Errorf("result is %d, want 4950", result)
+			}
+			if supplied != 1 {
+				t.Errorf("supplier is called %d times, want 1", supplied)
+			}
+		}
+	})
+
+	t.Run("IdentityFinish", func(t *testing.T) {
+		collector := &Collector[int, []int, []int]{
+			supplier: func() []int {
+				return nil
+			},
+			combiner: func(a, b []int) []int {
+				return a
+			},
+			finisher: func(a []int) []int {
+				t.Errorf("finisher is called")
+				return a
+			},
+			characteristics: IdentityFinish,
+		}
+
+		result := CollectByCollector(Empty[int](), collector)
+		if result != nil {
+			t.Errorf("result is %v, want nil", result)
+		}
+	})
+}
+
+func TestCollectors_NewCollector(t *testing.T) {
+	// longest collects the longest strings
+	longest := NewCollector(
+		func() *[]string {
+			return new([]string)
+		},
+		func(a *[]string, s string) {
+			switch {
+			case len(*a) == 0 || len(s) > len((*a)[0]):
+				*a = []string{s}
+			case len(s) == len((*a)[0]):
+				*a = append(*a, s)
+			}
+		},
+		func(a, b *[]string) *[]string {
+			switch {
+			case len(*b) == 0:
+				return a
+			case len(*a) == 0 || len((*b)[0]) > len((*a)[0]):
+				return b
+			case len((*b)[0]) == len((*a)[0]):
+				*a = append(*a, *b...)
+			}
+			return a
+		},
+		func(a *[]string) []string {
+			return *a
+		},
+	)
+
+	data := []string{"go", "stream", "java", "golang", "c", "rust"}
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(data...)
+		if parallel {
+			s = s.ParallelN(3)
+		}
+
+		result := CollectByCollector(s,
+			GroupingByCollector(func(s string) bool {
+				return strings.HasPrefix(s, "go")
+			}, longest))
+		want := map[bool][]string{
+			true:  {"golang"},
+			false: {"stream"},
+		}
+		if !maps.EqualFunc(result, want, slices.Equal[[]string]) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	c := NewCollector(ToSetCollector[int]().Supplier(),
+		ToSetCollector[int]().Accumulator(),
+		ToSetCollector[int]().Combiner(),
+		ToSetCollector[int]().Finisher(),
+		Unordered, IdentityFinish)
+	if c.Characteristics() != Unordered|IdentityFinish {
+		t.Errorf("c.Characteristics() is %v, want %v",
+			c.Characteristics(), Unordered|IdentityFinish)
+	}
+}
+
 func TestCollectors_JoiningCollector(t *testing.T) {
 	data := []string{"hello", "world", "こんにちは", "世界"}
 	result := CollectByCollector(Of(data...), JoiningCollector(" "))
@@ -91,6 +243,33 @@ func TestCollectors_JoiningCollector(t *testing.T) {
 	}
 }
 
+func TestCollectors_JoiningCollector3(t *testing.T) {
+	numbers := Map(Range(0, 100), strconv.Itoa).ToSlice()
+
+	for _, tc := range [...]struct {
+		data []string
+		want string
+	}{
+		{data: nil, want: "[]"},
+		{data: []string{""}, want: "[]"},
+		{data: []string{"", ""}, want: "[, ]"},
+		{data: []string{"a", "", "b"}, want: "[a, , b]"},
+		{data: numbers, want: "[" + strings.Join(numbers, ", ") + "]"},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s, JoiningCollector3(", ", "[", "]"))
+			if result != tc.want {
+				t.Errorf("[%v] result is %q, want %q", parallel, result, tc.want)
+			}
+		}
+	}
+}
+
 func TestCollectors_MappingCollector(t *testing.T) {
 	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 	result := CollectByCollector(Of(data...),
@@ -275,6 +454,192 @@ func TestCollectors_GroupingByCollector(t *testing.T) {
 	})
 }
 
+func TestCollectors_GroupingBySortedCollector(t *testing.T) {
+	words := []string{"go", "stream", "java", "golang", "c", "rust", "scala"}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(words...)
+		if parallel {
+			s = s.ParallelN(3)
+		}
+
+		result := CollectByCollector(s, GroupingBySortedCollector(
+			func(w string) int { return len(w) },
+			ToSliceCollector[string]()))
+		want := []Entry[int, []string]{
+			{Key: 1, Value: []string{"c"}},
+			{Key: 2, Value: []string{"go"}},
+			{Key: 4, Value: []string{"java", "rust"}},
+			{Key: 5, Value: []string{"scala"}},
+			{Key: 6, Value: []string{"stream", "golang"}},
+		}
+		if !slices.EqualFunc(result, want, func(e1, e2 Entry[int, []string]) bool {
+			return e1.Key == e2.Key && slices.Equal(e1.Value, e2.Value)
+		}) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestCollectors_CountingByCollector(t *testing.T) {
+	words := strings.Fields("a b c a b a")
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(words...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s, CountingByCollector(Identity[string]))
+		want := map[string]int64{"a": 3, "b": 2, "c": 1}
+		if !maps.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	}
+}
+
+func TestCollectors_ModeCollector(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []string
+		want []string
+	}{
+		{data: nil, want: []string{}},
+		{data: []string{"a"}, want: []string{"a"}},
+		{data: []string{"a", "b", "b", "c", "b", "a"}, want: []string{"b"}},
+		{data: []string{"a", "b", "c", "b", "a"}, want: []string{"a", "b"}},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s, ModeCollector[string]())
+			slices.Sort(result)
+			if result == nil || !slices.Equal(result, tc.want) {
+				t.Errorf("[%v] %v: result is %v, want %v",
+					parallel, tc.data, result, tc.want)
+			}
+		}
+	}
+}
+
+func TestCollectors_DuplicatesCollector(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []string
+		want map[string]int64
+	}{
+		{data: nil, want: map[string]int64{}},
+		{data: []string{"a", "b", "c"}, want: map[string]int64{}},
+		{data: []string{"a", "b", "b", "c", "b", "a"},
+			want: map[string]int64{"a": 2, "b": 3}},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s, DuplicatesCollector[string]())
+			if !maps.Equal(result, tc.want) {
+				t.Errorf("[%v] %v: result is %v, want %v",
+					parallel, tc.data, result, tc.want)
+			}
+		}
+	}
+}
+
+func TestCollectors_ApproxDistinctCollector(t *testing.T) {
+	type point struct{ x, y int }
+
+	for _, n := range [...]int{0, 10, 1000, 100_000} {
+		for _, parallel := range [...]bool{false, true} {
+			// each value appears twice
+			s := Map(Range(0, 2*n), func(i int) int { return i / 2 })
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s, ApproxDistinctCollector[int](12))
+			if math.Abs(float64(result)-float64(n)) > float64(n)*0.08 {
+				t.Errorf("[%v] result is %d, want about %d", parallel, result, n)
+			}
+
+			points := Map(Range(0, n), func(i int) point { return point{i, -i} })
+			if parallel {
+				points = points.Parallel()
+			}
+
+			result = CollectByCollector(points, ApproxDistinctCollector[point](12))
+			if math.Abs(float64(result)-float64(n)) > float64(n)*0.08 {
+				t.Errorf("[%v] result is %d, want about %d", parallel, result, n)
+			}
+		}
+	}
+}
+
+func TestCollectors_ToFrequencyRankedSliceCollector(t *testing.T) {
+	data := []string{"c", "a", "b", "b", "a", "d", "b", "e", "e"}
+
+	for _, tc := range [...]struct {
+		limit int
+		want  []string
+	}{
+		{limit: -1, want: []string{"b", "a", "e", "c", "d"}},
+		{limit: 0, want: []string{}},
+		{limit: 2, want: []string{"b", "a"}},
+		{limit: 10, want: []string{"b", "a", "e", "c", "d"}},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.ParallelN(3)
+			}
+
+			result := CollectByCollector(s,
+				ToFrequencyRankedSliceCollector[string](tc.limit))
+			if !slices.Equal(result, tc.want) {
+				t.Errorf("[%v] limit %d: result is %v, want %v",
+					parallel, tc.limit, result, tc.want)
+			}
+		}
+	}
+}
+
+func TestCollectors_HistogramCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 1000)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s, HistogramCollector(
+			func(t int) int { return t / 300 }, 4))
+		want := []int64{300, 300, 300, 100}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestCollectors_HistogramByBoundariesCollector(t *testing.T) {
+	data := []float64{-1.0, 0.0, 0.5, 1.0, 1.0, 2.5, 3.0, 10.0}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(data...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s,
+			HistogramByBoundariesCollector(0.0, 1.0, 3.0))
+		want := []int64{1, 2, 3, 2}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
 func TestCollectors_PartitioningByToSliceCollector(t *testing.T) {
 	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 
@@ -310,6 +675,46 @@ func TestCollectors_PartitioningByCollector(t *testing.T) {
 	})
 }
 
+func TestCollectors_TeeingCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(1, 101)
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := CollectByCollector(s, TeeingCollector(
+			SummingCollector(Identity[int]),
+			CountingCollector[int](),
+			func(sum int, count int64) float64 {
+				return float64(sum) / float64(count)
+			},
+		))
+		if result != 50.5 {
+			t.Errorf("[%v] result is %v, want 50.5", parallel, result)
+		}
+	}
+}
+
+func TestCollectors_PairCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := CollectByCollector(s, PairCollector(
+			FilteringCollector(func(t int) bool { return t%10 == 0 },
+				ToSliceCollector[int]()),
+			CountingCollector[int](),
+		))
+		wantFirst := []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}
+		if !slices.Equal(result.First, wantFirst) || result.Second != 100 {
+			t.Errorf("[%v] result is %v, want %v",
+				parallel, result, PairOf(wantFirst, 100))
+		}
+	}
+}
+
 func TestCollectors_ToMapCollector(t *testing.T) {
 	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 
@@ -336,6 +741,97 @@ func TestCollectors_ToMapCollector(t *testing.T) {
 	}
 }
 
+func TestCollectors_ToMapWithSupplierCollector(t *testing.T) {
+	type parity map[string]int
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(1, 11)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(
+			s,
+			ToMapWithSupplierCollector(
+				func(t int) string {
+					if t&1 == 0 {
+						return "even"
+					}
+					return "odd"
+				},
+				Identity[int],
+				func(v1, v2 int) int { return v1 + v2 },
+				func() parity { return make(parity, 2) },
+			),
+		)
+		want := parity{"even": 2 + 4 + 6 + 8 + 10, "odd": 1 + 3 + 5 + 7 + 9}
+		if !maps.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestCollectors_ToOrderedMapCollector(t *testing.T) {
+	words := []string{"go", "stream", "java", "golang", "c", "rust", "scala"}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(words...)
+		if parallel {
+			s = s.ParallelN(3)
+		}
+
+		result := CollectByCollector(s, ToOrderedMapCollector(
+			func(w string) string { return w[:1] },
+			func(w string) []string { return []string{w} },
+			func(v1, v2 []string) []string { return append(v1, v2...) },
+		))
+
+		wantKeys := []string{"g", "s", "j", "c", "r"}
+		if !slices.Equal(result.Keys(), wantKeys) {
+			t.Errorf("[%v] result.Keys() is %v, want %v",
+				parallel, result.Keys(), wantKeys)
+		}
+		if v, ok := result.Get("s"); !ok || !slices.Equal(v, []string{"stream", "scala"}) {
+			t.Errorf("[%v] result.Get(s) is %v, %v, want [stream scala], true",
+				parallel, v, ok)
+		}
+		if _, ok := result.Get("x"); ok {
+			t.Errorf("[%v] result.Get(x) is present", parallel)
+		}
+		if result.Len() != len(wantKeys) {
+			t.Errorf("[%v] result.Len() is %d, want %d",
+				parallel, result.Len(), len(wantKeys))
+		}
+	}
+}
+
+func TestCollectors_ToSyncMapCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 1000)
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := CollectByCollector(s, ToSyncMapCollector(
+			Identity[int],
+			strconv.Itoa,
+		))
+
+		count := 0
+		result.Range(func(key, value any) bool {
+			count++
+			if want := strconv.Itoa(key.(int)); value != want {
+				t.Errorf("[%v] result[%v] is %v, want %v",
+					parallel, key, value, want)
+			}
+			return true
+		})
+		if count != 1000 {
+			t.Errorf("[%v] count is %d, want 1000", parallel, count)
+		}
+	}
+}
+
 func TestCollectors_SummarizingCollector(t *testing.T) {
 	count := 1000
 	s := Iterate(1, func(t int) int {
@@ -355,11 +851,11 @@ func TestCollectors_SummarizingCollector(t *testing.T) {
 	if result.GetMin() != 1 {
 		t.Errorf("result.GetMin() is %d, want 1", result.GetMin())
 	}
-	if result.GetMax() != int64(count) {
+	if result.GetMax() != count {
 		t.Errorf("result.GetMax() is %d, want %d", result.GetMax(), count)
 	}
 	wantSum := (1 + count) * count / 2
-	if result.GetSum() != int64(wantSum) {
+	if result.GetSum() != wantSum {
 		t.Errorf("result.GetSum() is %d, want %d", result.GetSum(), wantSum)
 	}
 	wantAverage := float64(wantSum) / float64(count)
@@ -369,6 +865,127 @@ func TestCollectors_SummarizingCollector(t *testing.T) {
 	t.Logf("result : %v\n", result)
 }
 
+func TestCollectors_SummarizingCollector_Float(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(0.5, 1.5, -0.25)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s, SummarizingCollector(Identity[float64]))
+		if result.GetCount() != 3 {
+			t.Errorf("[%v] result.GetCount() is %d, want 3", parallel, result.GetCount())
+		}
+		if result.GetSum() != 1.75 {
+			t.Errorf("[%v] result.GetSum() is %v, want 1.75", parallel, result.GetSum())
+		}
+		if result.GetMin() != -0.25 {
+			t.Errorf("[%v] result.GetMin() is %v, want -0.25", parallel, result.GetMin())
+		}
+		if result.GetMax() != 1.5 {
+			t.Errorf("[%v] result.GetMax() is %v, want 1.5", parallel, result.GetMax())
+		}
+		if result.GetAverage() != 0.5833333333333334 {
+			t.Errorf("[%v] result.GetAverage() is %v, want 0.5833333333333334",
+				parallel, result.GetAverage())
+		}
+	}
+
+	result := CollectByCollector(Of(1.0, math.NaN()),
+		SummarizingCollector(Identity[float64]))
+	if !math.IsNaN(result.GetMax()) || !math.IsNaN(result.GetSum()) {
+		t.Errorf("result is %v, want NaN max and sum", result)
+	}
+
+	result = CollectByCollector(Empty[float64](),
+		SummarizingCollector(Identity[float64]))
+	if !math.IsInf(result.GetMin(), 1) || !math.IsInf(result.GetMax(), -1) {
+		t.Errorf("result is %v, want +Inf min and -Inf max", result)
+	}
+}
+
+func TestCollectors_SummarizingCollector_Int8(t *testing.T) {
+	result := CollectByCollector(Of[int8](100, 120, -5),
+		SummarizingCollector(Identity[int8]))
+	if result.GetMin() != -5 || result.GetMax() != 120 {
+		t.Errorf("result.GetMin(), GetMax() are %d, %d, want -5, 120",
+			result.GetMin(), result.GetMax())
+	}
+	// the average is not affected by the overflow of the sum in int8
+	if result.GetAverage() != 215.0/3 {
+		t.Errorf("result.GetAverage() is %v, want %v",
+			result.GetAverage(), 215.0/3)
+	}
+}
+
+func TestCollectors_SummarizingFloat64Collector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(0.5, 1.5, -0.25)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s,
+			SummarizingFloat64Collector(Identity[float64]))
+		if result.GetCount() != 3 {
+			t.Errorf("[%v] result.GetCount() is %d, want 3", parallel, result.GetCount())
+		}
+		if result.GetSum() != 1.75 {
+			t.Errorf("[%v] result.GetSum() is %v, want 1.75", parallel, result.GetSum())
+		}
+		if result.GetMin() != -0.25 {
+			t.Errorf("[%v] result.GetMin() is %v, want -0.25", parallel, result.GetMin())
+		}
+		if result.GetMax() != 1.5 {
+			t.Errorf("[%v] result.GetMax() is %v, want 1.5", parallel, result.GetMax())
+		}
+	}
+
+	// Kahan summation keeps the small values
+	data := Generate(func() float64 { return 1.0 }).Limit(1000).ToSlice()
+	result := CollectByCollector(Of(append([]float64{1e16}, data...)...),
+		SummarizingFloat64Collector(Identity[float64]))
+	if result.GetSum() != 1e16+1000 {
+		t.Errorf("result.GetSum() is %v, want %v", result.GetSum(), 1e16+1000)
+	}
+
+	result = CollectByCollector(Of(1.0, math.NaN(), 2.0),
+		SummarizingFloat64Collector(Identity[float64]))
+	if !math.IsNaN(result.GetMin()) || !math.IsNaN(result.GetAverage()) {
+		t.Errorf("result is %v, want NaN min and average", result)
+	}
+
+	result = CollectByCollector(Empty[float64](),
+		SummarizingFloat64Collector(Identity[float64]))
+	if result.GetAverage() != 0 || !math.IsInf(result.GetMin(), 1) {
+		t.Errorf("result is %v, want 0 average and +Inf min", result)
+	}
+
+	for _, tc := range [...]struct {
+		data []float64
+		want float64
+	}{
+		{data: []float64{1, math.Inf(1), math.Inf(1)}, want: math.Inf(1)},
+		{data: []float64{1, math.Inf(-1)}, want: math.Inf(-1)},
+		{data: []float64{math.Inf(1), math.Inf(-1)}, want: math.NaN()},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.ParallelN(2)
+			}
+
+			result := CollectByCollector(s,
+				SummarizingFloat64Collector(Identity[float64]))
+			sum := result.GetSum()
+			if !(sum == tc.want || math.IsNaN(sum) && math.IsNaN(tc.want)) {
+				t.Errorf("[%v] %v: result.GetSum() is %v, want %v",
+					parallel, tc.data, sum, tc.want)
+			}
+		}
+	}
+}
+
 func TestCollectors_SummingCollector(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		s := Empty[string]()
@@ -447,3 +1064,252 @@ func TestCollectors_AveragingFloat64Collector(t *testing.T) {
 		t.Errorf("average is %e, want %e", average, wantAverage)
 	}
 }
+
+func TestCollectors_MomentsCollector(t *testing.T) {
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-9*max(1, math.Abs(b))
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(2.0, 4, 4, 4, 5, 5, 7, 9)
+		if parallel {
+			s = s.ParallelN(3)
+		}
+
+		result := CollectByCollector(s, MomentsCollector(Identity[float64]))
+		want := Moments{
+			Count: 8, Mean: 5, Variance: 4, Skewness: 0.65625, Kurtosis: -0.21875,
+		}
+		if result.Count != want.Count || !near(result.Mean, want.Mean) ||
+			!near(result.Variance, want.Variance) ||
+			!near(result.Skewness, want.Skewness) ||
+			!near(result.Kurtosis, want.Kurtosis) {
+			t.Errorf("[%v] result is %+v, want %+v", parallel, result, want)
+		}
+	}
+
+	// combining partial moments is the same as one pass
+	data := Float64s(rand.New(rand.NewSource(1))).Limit(10_000).ToSlice()
+	want := CollectByCollector(Of(data...), MomentsCollector(Identity[float64]))
+	result := CollectByCollector(Of(data...).ParallelN(4),
+		MomentsCollector(Identity[float64]))
+	if result.Count != want.Count || !near(result.Mean, want.Mean) ||
+		!near(result.Variance, want.Variance) ||
+		!near(result.Skewness, want.Skewness) ||
+		!near(result.Kurtosis, want.Kurtosis) {
+		t.Errorf("result is %+v, want %+v", result, want)
+	}
+
+	result = CollectByCollector(Empty[float64](), MomentsCollector(Identity[float64]))
+	if result != (Moments{}) {
+		t.Errorf("result is %+v, want %+v", result, Moments{})
+	}
+}
+
+func TestCollectors_TopNCollector(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+
+	for _, tc := range [...]struct {
+		dataSize int
+		n        int
+	}{
+		{dataSize: 0, n: 3},
+		{dataSize: 2, n: 3},
+		{dataSize: 1000, n: 0},
+		{dataSize: 1000, n: 10},
+	} {
+		data := rand.Perm(tc.dataSize)
+
+		var want []int
+		for i := tc.dataSize - 1; i >= 0 && len(want) < tc.n; i-- {
+			want = append(want, i)
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s, TopNCollector(tc.n, less))
+			if !slices.Equal(result, want) {
+				t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+			}
+		}
+	}
+}
+
+func TestCollectors_LastNCollector(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		n        int
+	}{
+		{dataSize: 0, n: 3},
+		{dataSize: 2, n: 3},
+		{dataSize: 1000, n: 0},
+		{dataSize: 1000, n: 1},
+		{dataSize: 1000, n: 10},
+	} {
+		want := Range(max(tc.dataSize-tc.n, 0), tc.dataSize).ToSlice()
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Range(0, tc.dataSize)
+			if parallel {
+				s = s.ParallelN(4)
+			}
+
+			result := CollectByCollector(s, LastNCollector[int](tc.n))
+			if !slices.Equal(result, want) {
+				t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+			}
+		}
+	}
+}
+
+func TestCollectors_PercentileCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(4, 1, 3, 2)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s, PercentileCollector[int](0, 0.5, 1))
+		want := []float64{1, 2.5, 4}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(rand.Perm(100_001)...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		quantiles := []float64{0.01, 0.5, 0.95, 0.99}
+		result := CollectByCollector(s, PercentileCollector[int](quantiles...))
+		for i, q := range quantiles {
+			want := q * 100_000
+			if math.Abs(result[i]-want) > 100_000*0.005 {
+				t.Errorf("[%v] quantile %v is %v, want about %v",
+					parallel, q, result[i], want)
+			}
+		}
+	}
+
+	result := CollectByCollector(Empty[int](), PercentileCollector[int](0.5))
+	if !math.IsNaN(result[0]) {
+		t.Errorf("result is %v, want NaN", result)
+	}
+}
+
+func TestCollectors_WeightedAveragingCollector(t *testing.T) {
+	type trade struct {
+		price  float64
+		volume float64
+	}
+
+	for _, tc := range [...]struct {
+		data []trade
+		want float64
+	}{
+		{data: nil, want: 0},
+		{data: []trade{{price: 10, volume: 0}}, want: 0},
+		{data: []trade{{price: 10, volume: 1}, {price: 20, volume: 3}}, want: 17.5},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.ParallelN(2)
+			}
+
+			result := CollectByCollector(s, WeightedAveragingCollector(
+				func(t trade) float64 { return t.price },
+				func(t trade) float64 { return t.volume },
+			))
+			if result != tc.want {
+				t.Errorf("[%v] %v: result is %v, want %v",
+					parallel, tc.data, result, tc.want)
+			}
+		}
+	}
+}
+
+func TestCollectors_VarianceCollector(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []float64
+		want float64
+	}{
+		{data: nil, want: 0},
+		{data: []float64{5}, want: 0},
+		{data: []float64{2, 4, 4, 4, 5, 5, 7, 9}, want: 4},
+		{data: []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16}, want: 22.5},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(tc.data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := CollectByCollector(s,
+				VarianceCollector(Identity[float64]))
+			if math.Abs(result-tc.want) > 1e-9 {
+				t.Errorf("[%v] %v: result is %v, want %v",
+					parallel, tc.data, result, tc.want)
+			}
+		}
+	}
+
+	// the variance of 0, 1, ..., n-1 is (n*n-1)/12
+	const n = 10_000
+	result := CollectByCollector(Range(0, n).Parallel(),
+		VarianceCollector(func(t int) float64 { return float64(t) }))
+	if want := float64(n*n-1) / 12; math.Abs(result-want) > 1e-6 {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+}
+
+func TestCollectors_StdDevCollector(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(2, 4, 4, 4, 5, 5, 7, 9)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		// The partial results of a parallel stream are combined with rounding
+		// errors, and their number depends on GOMAXPROCS: with 32 parts, the
+		// result is 1.9999999999999998.
+		result := CollectByCollector(s,
+			StdDevCollector(func(t int) float64 { return float64(t) }))
+		if math.Abs(result-2) > 1e-9 {
+			t.Errorf("[%v] result is %v, want 2", parallel, result)
+		}
+	}
+}
+
+func TestCollectors_MinMaxCollector(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(rand.Perm(1000)...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := CollectByCollector(s, MinMaxCollector(less))
+		want := MinMax[int]{Min: 0, Max: 999, Count: 1000}
+		if !result.IsPresent() || result.Get() != want {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+
+		s = Empty[int]()
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result = CollectByCollector(s, MinMaxCollector(less))
+		if result.IsPresent() {
+			t.Errorf("[%v] result is %v, want empty", parallel, result)
+		}
+	}
+}
@@ -1,7 +1,89 @@
-// Copyright © 2021 Yoshiki Shibata. All rights reserved.
+// Copyright © 2021, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
+import "slices"
+
+// Comparable is implemented by types which have natural order. CompareTo
+// returns a negative number, zero or a positive number as the receiver is
+// less than, equal to, or greater than o.
 type Comparable[T any] interface {
 	CompareTo(o T) int
 }
+
+// compareTo compares a and b by CompareTo.
+func compareTo[T Comparable[T]](a, b T) int {
+	return a.CompareTo(b)
+}
+
+// SortedComparable returns a stream consisting of the elements of stream,
+// sorted according to natural order defined by CompareTo. The sort is stable:
+// elements which compare equal keep their encounter order.
+func SortedComparable[T Comparable[T]](stream Stream[T]) Stream[T] {
+	gs := stream.(*genericStream[T])
+	dataSlice := gs.ToSlice()
+	slices.SortStableFunc(dataSlice, compareTo[T])
+	return ofCollected(gs, dataSlice)
+}
+
+// MaxComparable returns the maximum element of a stream according to
+// CompareTo.
+func MaxComparable[T Comparable[T]](stream Stream[T]) *Optional[T] {
+	return stream.Max(func(x, y T) bool {
+		return x.CompareTo(y) < 0
+	})
+}
+
+// MinComparable returns the minimum element of a stream according to
+// CompareTo.
+func MinComparable[T Comparable[T]](stream Stream[T]) *Optional[T] {
+	return stream.Min(func(x, y T) bool {
+		return x.CompareTo(y) < 0
+	})
+}
+
+// DistinctComparable returns a stream consisting of the distinct elements
+// (according to CompareTo) of this stream. The first element of equal ones
+// in encounter order is retained. The distinct elements seen are remembered
+// in sorted order, so each element is looked up by binary search.
+func DistinctComparable[T Comparable[T]](stream Stream[T]) Stream[T] {
+	s := stream.(*genericStream[T])
+	s.validateState()
+	s = s.inEncounterOrder()
+
+	gs := &genericStream[T]{
+		parallelCount: 1,
+		unordered:     s.unordered,
+		stages:        s.stages,
+		failure:       s.failure,
+		cancel:        s.cancel,
+		prevReq:       s.nextReq,
+		prevData:      s.nextData,
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[T]),
+	}
+
+	go func() {
+		var seen []T
+
+		for gs.getNextReq() {
+			for {
+				od, ok := gs.getPrevData()
+				if !ok {
+					gs.close()
+					return
+				}
+
+				i, found := slices.BinarySearchFunc(seen, od.data, compareTo[T])
+				if !found {
+					gs.nextData <- od
+					seen = slices.Insert(seen, i, od.data)
+					break
+				}
+			}
+		}
+		gs.close()
+	}()
+
+	return gs
+}
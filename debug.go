@@ -0,0 +1,31 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// stageStats holds the statistics of a named stage of a stream pipeline.
+type stageStats struct {
+	name  string
+	count atomic.Int64 // the number of elements passed through the stage
+}
+
+// String returns the names of the named stages of this stream pipeline,
+// each of which is followed by the number of elements passed through it.
+func (gs *genericStream[T]) String() string {
+	var b strings.Builder
+
+	b.WriteString("Stream[")
+	for i, stage := range gs.stages {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s:%d", stage.name, stage.count.Load())
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,92 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// EncodeJSON writes the elements of stream to w as a JSON array in encounter
+// order, followed by a newline, without collecting them into a slice.
+// A parallel stream is made sequential by Sequential, which collects the
+// elements only if the stream is ordered and some elements have been
+// dropped, such as by Filter. If encoding or writing fails, no more elements
+// are requested from stream and the error is returned.
+func EncodeJSON[T any](stream Stream[T], w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("[")
+	first := true
+	err := stream.Sequential().TryForEach(func(t T) error {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			bw.WriteString(",")
+		}
+		first = false
+		_, err = bw.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+
+	return bw.Flush()
+}
+
+// EncodeNDJSON writes the elements of stream to w as newline delimited JSON,
+// one JSON value per line in encounter order, as EncodeJSON does.
+// If encoding or writing fails, no more elements are requested from stream
+// and the error is returned.
+func EncodeNDJSON[T any](stream Stream[T], w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+
+	err := stream.Sequential().TryForEach(func(t T) error {
+		return encoder.Encode(t)
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// EncodeCSV writes the elements of stream to w as CSV records in encounter
+// order, each of which is produced by the record function. If header is not
+// nil, it is written as the first record. Records are flushed to w as the
+// internal buffer fills up, so the elements are not collected into a slice,
+// except for a parallel stream as described for EncodeJSON. If writing
+// fails, no more elements are requested from stream and the error is
+// returned.
+func EncodeCSV[T any](
+	stream Stream[T],
+	w io.Writer,
+	header []string,
+	record func(t T) []string,
+) error {
+	cw := csv.NewWriter(w)
+
+	if header != nil {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	err := stream.Sequential().TryForEach(func(t T) error {
+		return cw.Write(record(t))
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
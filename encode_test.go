@@ -0,0 +1,157 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func TestEncode_EncodeJSON(t *testing.T) {
+	for _, tc := range [...]struct {
+		points []point
+		want   string
+	}{
+		{points: nil, want: "[]\n"},
+		{points: []point{{1, 2}}, want: `[{"x":1,"y":2}]` + "\n"},
+		{
+			points: []point{{1, 2}, {3, 4}},
+			want:   `[{"x":1,"y":2},{"x":3,"y":4}]` + "\n",
+		},
+	} {
+		for _, parallel := range []bool{false, true} {
+			s := Of(tc.points...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			var b strings.Builder
+			if err := EncodeJSON(s, &b); err != nil {
+				t.Errorf("[%v] err is %v, want nil", parallel, err)
+			}
+			if b.String() != tc.want {
+				t.Errorf("[%v] result is %q, want %q", parallel, b.String(), tc.want)
+			}
+		}
+	}
+
+	t.Run("unsupported value", func(t *testing.T) {
+		var b strings.Builder
+		err := EncodeJSON(Of[any](1, func() {}, 3), &b)
+		if err == nil {
+			t.Errorf("err is nil, want an error")
+		}
+	})
+
+	t.Run("infinite parallel", func(t *testing.T) {
+		s := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Parallel(), func(v int) any {
+			if v == 10 {
+				return func() {}
+			}
+			return v
+		})
+
+		var b strings.Builder
+		if err := EncodeJSON(s, &b); err == nil {
+			t.Errorf("err is nil, want an error")
+		}
+	})
+}
+
+func TestEncode_EncodeNDJSON(t *testing.T) {
+	var b strings.Builder
+	err := EncodeNDJSON(Of(point{1, 2}, point{3, 4}).Parallel(), &b)
+	if err != nil {
+		t.Errorf("err is %v, want nil", err)
+	}
+
+	want := `{"x":1,"y":2}` + "\n" + `{"x":3,"y":4}` + "\n"
+	if b.String() != want {
+		t.Errorf("result is %q, want %q", b.String(), want)
+	}
+}
+
+func TestEncode_EncodeCSV(t *testing.T) {
+	record := func(p point) []string {
+		return []string{strconv.Itoa(p.X), strconv.Itoa(p.Y)}
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of(point{1, 2}, point{3, 4})
+		if parallel {
+			s = s.Parallel()
+		}
+
+		var b strings.Builder
+		err := EncodeCSV(s, &b, []string{"x", "y"}, record)
+		if err != nil {
+			t.Errorf("[%v] err is %v, want nil", parallel, err)
+		}
+
+		want := "x,y\n1,2\n3,4\n"
+		if b.String() != want {
+			t.Errorf("[%v] result is %q, want %q", parallel, b.String(), want)
+		}
+	}
+
+	var b strings.Builder
+	err := EncodeCSV(Of("a,b", `"c"`), &b, nil, func(s string) []string {
+		return []string{s}
+	})
+	if err != nil {
+		t.Errorf("err is %v, want nil", err)
+	}
+
+	want := `"a,b"` + "\n" + `"""c"""` + "\n"
+	if b.String() != want {
+		t.Errorf("result is %q, want %q", b.String(), want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestEncode_WriteError(t *testing.T) {
+	// enough elements to fill the buffer of bufio.Writer
+	naturals := func() Stream[int] {
+		return Iterate(0, func(v int) int {
+			return v + 1
+		})
+	}
+
+	err := EncodeNDJSON(naturals(), failingWriter{})
+	if err == nil || err.Error() != "write failed" {
+		t.Errorf("err is %v, want write failed", err)
+	}
+
+	err = EncodeNDJSON(naturals().Parallel(), failingWriter{})
+	if err == nil || err.Error() != "write failed" {
+		t.Errorf("[parallel] err is %v, want write failed", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := naturals()
+		if parallel {
+			s = s.Parallel()
+		}
+
+		err = EncodeCSV(s, failingWriter{}, nil, func(v int) []string {
+			return []string{strconv.Itoa(v)}
+		})
+		if err == nil || err.Error() != "write failed" {
+			t.Errorf("[%v] err is %v, want write failed", parallel, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrTimeout is the error of a stream aborted because its time limit,
+	// specified by WithTimeout or MapWithTimeout, was exceeded.
+	ErrTimeout = errors.New("gostream: stream timed out")
+
+	// ErrNoElements is the error returned by Single for an empty stream.
+	ErrNoElements = errors.New("gostream: stream has no elements")
+
+	// ErrMultipleElements is the error returned by Single for a stream which
+	// has more than one element.
+	ErrMultipleElements = errors.New("gostream: stream has more than one element")
+)
+
+// failure holds the error which aborted a stream pipeline.
+type failure struct {
+	lock sync.Mutex
+	err  error
+}
+
+// set records err unless an error has already been recorded.
+func (f *failure) set(err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *failure) get() error {
+	if f == nil {
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.err
+}
+
+// aborter aborts the workers of a stage of a stream pipeline with an error.
+type aborter struct {
+	failure *failure
+	aborted chan struct{} // closed when aborted
+	once    sync.Once
+}
+
+func newAborter(f *failure) *aborter {
+	return &aborter{
+		failure: f,
+		aborted: make(chan struct{}),
+	}
+}
+
+func (a *aborter) abort(err error) {
+	a.failure.set(err)
+	a.once.Do(func() { close(a.aborted) })
+}
+
+// isAborted reports whether the stage has been aborted.
+func (a *aborter) isAborted() bool {
+	select {
+	case <-a.aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (gs *genericStream[T]) Err() error {
+	return gs.failure.get()
+}
+
+// ensureFailure makes gs hold a failure shared with its downstream stages.
+func (gs *genericStream[T]) ensureFailure() *failure {
+	if gs.failure == nil {
+		gs.failure = &failure{}
+	}
+	return gs.failure
+}
@@ -210,26 +210,10 @@ func TestExample_00(t *testing.T) {
 	})
 
 	t.Run("Effective Java, 3rd p.212", func(t *testing.T) {
-		freq := CollectByCollector(
-			Of(words...),
-			GroupingByCollector(
-				func(w string) string { return strings.ToLower(w) },
-				CountingCollector[string](),
-			))
-		keySet := make([]string, 0, len(freq))
-		for k, _ := range freq {
-			keySet = append(keySet, k)
-		}
-		result := Of(keySet...).Sorted(
-			func(k1, k2 string) int {
-				switch {
-				case freq[k1] == freq[k2]:
-					return 0
-				case freq[k1] > freq[k2]:
-					return -1
-				}
-				return 1
-			}).Limit(10).ToSlice()
+		result := CollectByCollector(
+			Map(Of(words...), strings.ToLower),
+			ToFrequencyRankedSliceCollector[string](10),
+		)
 
 		t.Logf("result : %v\n", result)
 	})
@@ -318,6 +302,13 @@ func TestExample_07(t *testing.T) {
 	result := OptionalFlatMap(a, squareRoot)
 
 	t.Logf("result : %v", result)
+
+	// inverse and square root of the same value, computed independently
+	result = OptionalZip(inverse(4.0), squareRoot(4.0), func(a, b float64) float64 {
+		return a * b
+	})
+
+	t.Logf("result : %v", result)
 }
 
 func TestExample_RandomNumbers(t *testing.T) {
@@ -359,24 +350,15 @@ func TestExample_RandomNumbers(t *testing.T) {
 
 		const length = 20
 		interval := int64(math.MaxInt64 / (length / 2))
-		classifier := func(value int64) int {
-			for i := 0; i < length; i++ {
-				lower := math.MinInt64 + int64(i)*interval
-				upper := lower + interval
-
-				if lower <= value && value < upper {
-					return i
-				}
-			}
-			panic("Impossible")
+		bucket := func(value int64) int {
+			// offset from math.MinInt64
+			offset := uint64(value) ^ (1 << 63)
+			return min(int(offset/uint64(interval)), length-1)
 		}
 
 		result := CollectByCollector(
 			randomStream,
-			GroupingByCollector(
-				classifier,
-				CountingCollector[int64](),
-			),
+			HistogramCollector(bucket, length),
 		)
 
 		t.Logf("%v", result)
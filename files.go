@@ -1,4 +1,4 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
@@ -7,6 +7,9 @@ import (
 	"os"
 )
 
+// FileLines returns a sequential ordered Stream of the lines of the file
+// named by filepath. The file is closed when all lines have been read or the
+// stream has been closed.
 func FileLines(filepath string) (Stream[string], error) {
 	f, err := os.Open(filepath)
 	if err != nil {
@@ -16,37 +19,36 @@ func FileLines(filepath string) (Stream[string], error) {
 	input := bufio.NewScanner(f)
 	input.Split(bufio.ScanLines)
 
-	nextReq := make(chan struct{})
-	nextData := make(chan orderedData[string])
-	prevDone := make(chan struct{})
+	return scannerStream(input, func() { f.Close() }), nil
+}
+
+// scannerStream returns a sequential ordered Stream of the tokens scanned by
+// input. done is called when the scanning has finished or the stream has been
+// closed.
+func scannerStream(input *bufio.Scanner, done func()) Stream[string] {
+	gs := &genericStream[string]{
+		parallelCount: 1,
+		dense:         true,
+		cancel:        newCanceler(),
+		prevDone:      make(chan struct{}),
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[string]),
+	}
 
 	go func() {
 		i := 0
-		for range nextReq {
-			if !input.Scan() {
-				close(nextData)
-				close(prevDone)
-				f.Close()
-				go func() {
-					for range nextReq {
-					}
-				}()
-				return
-			}
-			nextData <- orderedData[string]{
+		for gs.getNextReq() && input.Scan() {
+			gs.nextData <- orderedData[string]{
 				order: uint64(i),
 				data:  input.Text(),
 			}
+			i++
 		}
-		close(nextData)
-		close(prevDone)
-		f.Close()
+		close(gs.nextData)
+		close(gs.prevDone)
+		done()
+		gs.discard(gs.nextReq)
 	}()
 
-	return &genericStream[string]{
-		parallelCount: 1,
-		prevDone:      prevDone,
-		nextReq:       nextReq,
-		nextData:      nextData,
-	}, nil
+	return gs
 }
@@ -1,7 +1,16 @@
-// Copyright © 2020, 2022 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2022, 2026 Yoshiki Shibata. All rights reserved.
 
 package function
 
 // BiConsumer represents an operation that accepts two input arguments and
 // returns no result.
 type BiConsumer[T, U any] func(t T, u U)
+
+// AndThen returns a BiConsumer which performs c, and then after, on its
+// inputs.
+func (c BiConsumer[T, U]) AndThen(after BiConsumer[T, U]) BiConsumer[T, U] {
+	return func(t T, u U) {
+		c(t, u)
+		after(t, u)
+	}
+}
@@ -1,7 +1,31 @@
-// Copyright © 2020, 2022 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2022, 2026 Yoshiki Shibata. All rights reserved.
 
 package function
 
 // BiFunction represents a function that accepts two arguments and produces
 // a result.
 type BiFunction[T, U, R any] func(t T, u U) R
+
+// Bind1 returns a Function which applies f with t bound to its first
+// argument.
+func Bind1[T, U, R any](f BiFunction[T, U, R], t T) Function[U, R] {
+	return func(u U) R {
+		return f(t, u)
+	}
+}
+
+// Bind2 returns a Function which applies f with u bound to its second
+// argument.
+func Bind2[T, U, R any](f BiFunction[T, U, R], u U) Function[T, R] {
+	return func(t T) R {
+		return f(t, u)
+	}
+}
+
+// Curry returns the curried form of f: a Function which accepts the first
+// argument of f, and returns a Function which accepts the second.
+func Curry[T, U, R any](f BiFunction[T, U, R]) Function[T, Function[U, R]] {
+	return func(t T) Function[U, R] {
+		return Bind1(f, t)
+	}
+}
@@ -0,0 +1,6 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// BiPredicate represents a predicate (bool-valued function) of two arguments.
+type BiPredicate[T, U any] func(t T, u U) bool
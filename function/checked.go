@@ -0,0 +1,15 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// CheckedFunction represents a function that accepts one argument and
+// produces a result, or fails with an error.
+type CheckedFunction[T, R any] func(t T) (R, error)
+
+// CheckedConsumer represents an operation that accepts a single input
+// argument and returns no result, but may fail with an error.
+type CheckedConsumer[T any] func(t T) error
+
+// CheckedPredicate represents a predicate (bool-valued function) of one
+// argument, which may fail with an error.
+type CheckedPredicate[T any] func(t T) (bool, error)
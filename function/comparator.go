@@ -0,0 +1,105 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+import "cmp"
+
+// Comparator represents a comparison function, which returns a negative
+// number, zero or a positive number as the first argument is less than,
+// equal to, or greater than the second.
+type Comparator[T any] func(a, b T) int
+
+// NaturalOrder returns a Comparator which compares values in natural order.
+func NaturalOrder[T cmp.Ordered]() Comparator[T] {
+	return cmp.Compare[T]
+}
+
+// FromLess returns a Comparator equivalent to the less function.
+func FromLess[T any](less func(a, b T) bool) Comparator[T] {
+	return func(a, b T) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		}
+		return 0
+	}
+}
+
+// ComparingBy returns a Comparator which compares values by natural order of
+// the keys extracted by the key function.
+func ComparingBy[T any, K cmp.Ordered](key Function[T, K]) Comparator[T] {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
+}
+
+// ComparingByLess returns a Comparator which compares values by the keys
+// extracted by the key function, according to the less function of keys.
+func ComparingByLess[T, K any](key Function[T, K], less func(a, b K) bool) Comparator[T] {
+	keyComparator := FromLess(less)
+	return func(a, b T) int {
+		return keyComparator(key(a), key(b))
+	}
+}
+
+// NilFirst returns a Comparator of pointers which considers nil to be less
+// than non-nil, and compares the values pointed to by c if both are non-nil.
+// c is never called with a nil pointer.
+func NilFirst[T any](c Comparator[T]) Comparator[*T] {
+	return nilComparator(c, true)
+}
+
+// NilLast returns a Comparator of pointers which considers nil to be greater
+// than non-nil, and compares the values pointed to by c if both are non-nil.
+// c is never called with a nil pointer.
+func NilLast[T any](c Comparator[T]) Comparator[*T] {
+	return nilComparator(c, false)
+}
+
+func nilComparator[T any](c Comparator[T], nilFirst bool) Comparator[*T] {
+	return func(a, b *T) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			if nilFirst {
+				return -1
+			}
+			return 1
+		case b == nil:
+			if nilFirst {
+				return 1
+			}
+			return -1
+		}
+		return c(*a, *b)
+	}
+}
+
+// Reversed returns a Comparator which imposes the reverse ordering of c.
+func (c Comparator[T]) Reversed() Comparator[T] {
+	return func(a, b T) int {
+		return c(b, a)
+	}
+}
+
+// ThenComparing returns a Comparator which compares values by c, and then
+// by other if they are equal according to c.
+func (c Comparator[T]) ThenComparing(other Comparator[T]) Comparator[T] {
+	return func(a, b T) int {
+		if r := c(a, b); r != 0 {
+			return r
+		}
+		return other(a, b)
+	}
+}
+
+// Less returns a less function equivalent to c, which reports whether a is
+// less than b.
+func (c Comparator[T]) Less() func(a, b T) bool {
+	return func(a, b T) bool {
+		return c(a, b) < 0
+	}
+}
@@ -1,7 +1,30 @@
-// Copyright © 2020, 2022 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2022, 2026 Yoshiki Shibata. All rights reserved.
 
 package function
 
 // Consumer represents an operation that accepts a single input argument and
 // returns no result.
 type Consumer[T any] func(t T)
+
+// AndThen returns a Consumer which performs c, and then after, on its input.
+func (c Consumer[T]) AndThen(after Consumer[T]) Consumer[T] {
+	return func(t T) {
+		c(t)
+		after(t)
+	}
+}
+
+// ConsumerErr returns a Consumer which performs the fallible action, and
+// passes the input and the error to onError if the action fails. If onError
+// is nil, the error is discarded. Note that the Consumer may be called
+// concurrently by a parallel stream, and so may onError.
+func ConsumerErr[T any](
+	action CheckedConsumer[T],
+	onError func(t T, err error),
+) Consumer[T] {
+	return func(t T) {
+		if err := action(t); err != nil && onError != nil {
+			onError(t, err)
+		}
+	}
+}
@@ -1,7 +1,21 @@
-// Copyright © 2020, 2022 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2022, 2026 Yoshiki Shibata. All rights reserved.
 
 package function
 
 // Function represents a function that accepts one argument and produces a
 // result
 type Function[T, R any] func(t T) R
+
+// Compose returns a Function which applies f to its input, and then applies
+// g to the result.
+func Compose[A, B, C any](f Function[A, B], g Function[B, C]) Function[A, C] {
+	return func(a A) C {
+		return g(f(a))
+	}
+}
+
+// AndThen returns a Function which applies f to its input, and then applies
+// after to the result. To change the result type, use Compose instead.
+func (f Function[T, R]) AndThen(after Function[R, R]) Function[T, R] {
+	return Compose(f, after)
+}
@@ -0,0 +1,11 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// IndexedFunction represents a function that accepts the index of an
+// element in encounter order and the element, and produces a result.
+type IndexedFunction[T, R any] func(index int64, t T) R
+
+// IndexedPredicate represents a predicate (bool-valued function) that
+// accepts the index of an element in encounter order and the element.
+type IndexedPredicate[T any] func(index int64, t T) bool
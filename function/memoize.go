@@ -0,0 +1,118 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Memoize returns a Supplier which calls s only once, at the first call, and
+// returns the same result thereafter. The returned Supplier is safe for
+// concurrent use.
+func Memoize[T any](s Supplier[T]) Supplier[T] {
+	var once sync.Once
+	var result T
+	return func() T {
+		once.Do(func() {
+			result = s()
+		})
+		return result
+	}
+}
+
+// MemoizeFunc returns a Function which caches the results of f for each
+// input. The returned Function is safe for concurrent use, but f may be
+// called more than once for an input if it is called concurrently before
+// the result is cached. The cache grows without bound: use MemoizeFuncN to
+// limit its size.
+func MemoizeFunc[T comparable, R any](f Function[T, R]) Function[T, R] {
+	var mu sync.Mutex
+	cache := make(map[T]R)
+	return func(t T) R {
+		mu.Lock()
+		r, ok := cache[t]
+		mu.Unlock()
+		if ok {
+			return r
+		}
+
+		r = f(t)
+		mu.Lock()
+		cache[t] = r
+		mu.Unlock()
+		return r
+	}
+}
+
+// MemoizeFuncN returns a Function which caches the results of f for at most
+// n recently used inputs, as MemoizeFunc does. MemoizeFuncN panics if n is
+// not positive.
+func MemoizeFuncN[T comparable, R any](f Function[T, R], n int) Function[T, R] {
+	if n <= 0 {
+		panic("n must be positive")
+	}
+
+	var mu sync.Mutex
+	cache := newLRUCache[T, R](n)
+	return func(t T) R {
+		mu.Lock()
+		r, ok := cache.get(t)
+		mu.Unlock()
+		if ok {
+			return r
+		}
+
+		r = f(t)
+		mu.Lock()
+		cache.put(t, r)
+		mu.Unlock()
+		return r
+	}
+}
+
+// lruCache is a cache which evicts the least recently used entry when the
+// number of entries exceeds its capacity.
+type lruCache[K comparable, V any] struct {
+	capacity int
+	order    *list.List // of *lruEntry, the most recently used first
+	entries  map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRUCache[K comparable, V any](capacity int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+}
+
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry[K, V]).value, true
+}
+
+func (c *lruCache[K, V]) put(key K, value V) {
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
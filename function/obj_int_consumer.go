@@ -0,0 +1,7 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// ObjIntConsumer represents an operation that accepts an object-valued and
+// an int-valued argument, and returns no result.
+type ObjIntConsumer[T any] func(t T, value int)
@@ -1,6 +1,94 @@
-// Copyright © 2020, 2022 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2022, 2026 Yoshiki Shibata. All rights reserved.
 
 package function
 
+import "cmp"
+
 // Predicate represents a predicate (bool-valued function) of one argument.
 type Predicate[T any] func(t T) bool
+
+// Between returns a Predicate which tests if a value is between lo and hi,
+// inclusive.
+func Between[T cmp.Ordered](lo, hi T) Predicate[T] {
+	return func(t T) bool {
+		return lo <= t && t <= hi
+	}
+}
+
+// In returns a Predicate which tests if a value is equal to one of values.
+func In[T comparable](values ...T) Predicate[T] {
+	set := make(map[T]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return func(t T) bool {
+		_, ok := set[t]
+		return ok
+	}
+}
+
+// NotNil returns a Predicate which tests if a pointer is not nil.
+func NotNil[T any]() Predicate[*T] {
+	return func(t *T) bool {
+		return t != nil
+	}
+}
+
+// And returns a Predicate which tests if all of predicates are true. The
+// predicates are evaluated in order, and short-circuited by the first false
+// one. And with no predicates always returns true.
+func And[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(t T) bool {
+		for _, p := range predicates {
+			if !p(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a Predicate which tests if any of predicates is true. The
+// predicates are evaluated in order, and short-circuited by the first true
+// one. Or with no predicates always returns false.
+func Or[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(t T) bool {
+		for _, p := range predicates {
+			if p(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a Predicate which is the negation of p.
+func Not[T any](p Predicate[T]) Predicate[T] {
+	return func(t T) bool {
+		return !p(t)
+	}
+}
+
+// IsEqual returns a Predicate which tests if a value is equal to v.
+func IsEqual[T comparable](v T) Predicate[T] {
+	return func(t T) bool {
+		return t == v
+	}
+}
+
+// And returns a Predicate which is the short-circuiting logical AND of p
+// and other.
+func (p Predicate[T]) And(other Predicate[T]) Predicate[T] {
+	return And(p, other)
+}
+
+// Or returns a Predicate which is the short-circuiting logical OR of p and
+// other.
+func (p Predicate[T]) Or(other Predicate[T]) Predicate[T] {
+	return Or(p, other)
+}
+
+// Negate returns a Predicate which is the negation of p.
+func (p Predicate[T]) Negate() Predicate[T] {
+	return Not(p)
+}
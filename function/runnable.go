@@ -0,0 +1,7 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// Runnable represents an operation that accepts no arguments and returns no
+// result.
+type Runnable func()
@@ -0,0 +1,6 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package function
+
+// ToIntFunction represents a function that produces an int-valued result.
+type ToIntFunction[T any] func(t T) int
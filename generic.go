@@ -1,13 +1,15 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
 	"fmt"
+	"math/rand"
 	"runtime"
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/YoshikiShibata/gostream/function"
 )
@@ -23,16 +25,67 @@ type genericStream[T any] struct {
 
 	parallel      bool
 	parallelCount int
-	ordered       bool
+
+	// the encounter order of elements need not be kept
+	unordered bool
+
+	// the orders of elements are consecutive from zero, that is, no element
+	// has been dropped since the source.
+	dense bool
+
+	// the elements may arrive out of encounter order even if they are
+	// requested one at a time, because the parts of a split-based stream
+	// are served in parallel. Otherwise, a consumer which requests one
+	// element at a time receives them in encounter order, because only the
+	// requested element is in flight through the pipeline.
+	reordered bool
 
 	terminalCloseCount int
+	terminalClosed     bool
+
+	// named stages of the pipeline up to this stream
+	stages []*stageStats
+
+	// the error which aborted the pipeline up to this stream
+	failure *failure
+
+	// cancels the goroutines of the pipeline up to this stream
+	cancel *canceler
 
 	prevReq  chan struct{}
 	prevData chan orderedData[T]
 	prevDone chan struct{}
 
+	// closing is closed when one of the goroutines of a parallel stream has
+	// closed the stream, so that the others stop waiting for a request: the
+	// request taken by the goroutine which has found the end of the
+	// elements would never be answered, and a consumer which requests one
+	// element at a time would wait for it forever.
+	closing chan struct{}
+
 	nextReq  chan struct{}
 	nextData chan orderedData[T]
+
+	// pull returns the next element of a sequential stream whose pipeline
+	// is fused into an iterator run on the caller's goroutine, without
+	// goroutines and channels. It is nil once the stream is served through
+	// its channels, see validateState.
+	pull pullFunc[T]
+
+	// split splits the elements of a stream from a sized source into n
+	// contiguous parts, each of which is pulled independently by a
+	// goroutine of a parallel stream. It is nil if the source is not sized
+	// or a stage which can't be applied to each part is fused.
+	split func(n int) []pullFunc[T]
+
+	// fused returns n functions, each of which pulls the elements of a
+	// channel-based stream through the stateless stages fused onto it, such
+	// as Filter, Map and Peek, so that they are run in one stage of parallel
+	// goroutines instead of a stage for each. The elements are interleaved
+	// among the functions in arrival order. release closes the channel-based
+	// stream once all the functions are done.
+	fused   func(n int) []pullFunc[T]
+	release func()
 }
 
 var (
@@ -43,18 +96,106 @@ func newGenericStream[T any](gs *genericStream[T]) *genericStream[T] {
 	return &genericStream[T]{
 		parallel:      gs.parallel,
 		parallelCount: gs.parallelCount,
+		unordered:     gs.unordered,
+		reordered:     gs.reordered,
 
 		terminalCloseCount: gs.terminalCloseCount,
 
+		stages:  gs.stages,
+		failure: gs.failure,
+		cancel:  gs.cancel,
+
 		prevReq:  gs.nextReq,
 		prevData: gs.nextData,
 		prevDone: gs.prevDone,
+		closing:  make(chan struct{}),
 
 		nextReq:  make(chan struct{}, gs.parallelCount),
 		nextData: make(chan orderedData[T], gs.parallelCount*2),
 	}
 }
 
+// newDerivedStream returns a new sequential stream whose elements of type R
+// are derived from the elements of gs, and a function to get the next
+// element of gs. Closing the new stream closes gs.
+func newDerivedStream[R, T any](
+	gs *genericStream[T],
+) (*genericStream[R], func() (orderedData[T], bool)) {
+	newGS := &genericStream[R]{
+		parallel:      gs.parallel,
+		parallelCount: 1,
+		unordered:     gs.unordered,
+		reordered:     gs.reordered,
+
+		stages:  gs.stages,
+		failure: gs.failure,
+		cancel:  gs.cancel,
+
+		prevReq:  gs.nextReq,
+		prevDone: gs.prevDone,
+		closing:  make(chan struct{}),
+
+		nextReq:  make(chan struct{}),
+		nextData: make(chan orderedData[R]),
+	}
+
+	getPrevData := func() (orderedData[T], bool) {
+		newGS.prevReq <- struct{}{}
+		data, ok := <-gs.nextData
+		return data, ok
+	}
+
+	return newGS, getPrevData
+}
+
+// ofCollected returns a stream consisting of the elements collected from gs,
+// which continues the pipeline of gs.
+func ofCollected[R, T any](gs *genericStream[T], data []R) *genericStream[R] {
+	newGS := Of(data...).(*genericStream[R])
+	newGS.stages = gs.stages
+	newGS.failure = gs.failure
+	return newGS
+}
+
+// compareOrder compares a and b in encounter order.
+func compareOrder[T any](a, b orderedData[T]) int {
+	if a.order == b.order {
+		return 0
+	}
+	if a.order < b.order {
+		return -1
+	}
+	return 1
+}
+
+// mergeSortedData merges a and b, both of which are sorted by cmp, into
+// a new sorted slice.
+func mergeSortedData[T any](
+	a, b []orderedData[T],
+	cmp func(a, b orderedData[T]) int,
+) []orderedData[T] {
+	merged := make([]orderedData[T], 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if cmp(b[0], a[0]) < 0 {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// sortByOrder sorts ods in encounter order.
+func sortByOrder[T any](ods []orderedData[T]) {
+	slices.SortFunc(ods, compareOrder[T])
+}
+
+// validateState panics if gs has already been closed. If gs is pull-based,
+// split-based or has fused stages, validateState starts serving its
+// elements through its channels, because the caller is going to use them.
 func (gs *genericStream[T]) validateState() {
 	gs.lock.Lock()
 	defer gs.lock.Unlock()
@@ -62,6 +203,17 @@ func (gs *genericStream[T]) validateState() {
 	if gs.closed {
 		panic("stream has already been closed")
 	}
+
+	switch {
+	case gs.pull != nil:
+		go gs.servePull(gs.pull)
+	case gs.split != nil:
+		gs.serveSplit(gs.split)
+		gs.reordered = true
+	case gs.fused != nil:
+		gs.serveFused(gs.fused, gs.release)
+	}
+	gs.pull, gs.split, gs.fused = nil, nil, nil
 }
 
 func (gs *genericStream[T]) discard(c <-chan struct{}) {
@@ -72,6 +224,12 @@ func (gs *genericStream[T]) discard(c <-chan struct{}) {
 }
 
 func (gs *genericStream[T]) close() {
+	gs.closeAfter(nil)
+}
+
+// closeAfter closes gs like close, except that the previous stream is closed
+// after stopped is closed if stopped is not nil.
+func (gs *genericStream[T]) closeAfter(stopped <-chan struct{}) {
 	gs.lock.Lock()
 	defer gs.lock.Unlock()
 
@@ -81,37 +239,63 @@ func (gs *genericStream[T]) close() {
 
 	gs.parallelCount--
 	if gs.parallelCount > 0 {
+		if gs.closing != nil {
+			select {
+			case <-gs.closing:
+			default:
+				close(gs.closing)
+			}
+		}
 		return
 	}
 
 	close(gs.nextData)
-	close(gs.prevReq)
+	if stopped == nil {
+		close(gs.prevReq)
+	} else {
+		prevReq := gs.prevReq
+		go func() {
+			<-stopped
+			close(prevReq)
+		}()
+	}
 	gs.discard(gs.nextReq)
 	gs.closed = true
 }
 
+// terminalClose is called by each goroutine of a terminal operation when it
+// has finished, and closes the request channel of gs when all of them have
+// finished, so that the goroutines discarding requests terminate.
 func (gs *genericStream[T]) terminalClose() {
 	gs.lock.Lock()
 	defer gs.lock.Unlock()
 
-	if gs.terminalCloseCount == 0 {
+	if gs.terminalClosed {
 		return
 	}
 
-	gs.terminalCloseCount--
-	if gs.terminalCloseCount > 0 {
+	if gs.terminalCloseCount > 1 {
+		gs.terminalCloseCount--
 		return
 	}
 
+	gs.terminalClosed = true
 	close(gs.nextReq)
 }
 
+// getNextReq waits for a request for the next element, and reports false
+// if no more elements are requested, because gs or its source has been
+// closed, or the pipeline has been canceled.
 func (gs *genericStream[T]) getNextReq() bool {
 	select {
 	case _, ok := <-gs.nextReq:
 		return ok
 	case <-gs.prevDone:
 		return false
+	case <-gs.closing:
+		return false
+	case <-gs.cancel.canceled():
+		return false
 	}
 }
 
@@ -141,10 +325,23 @@ func (gs *genericStream[T]) terminalOpOrderedData(
 	gs.terminalClose()
 }
 
+// terminalOpMatch applies match to the elements of gs until match returns
+// false, and then cancels the pipeline, because no more elements are needed
+// by short-circuiting terminal operations.
 func (gs *genericStream[T]) terminalOpMatch(match func(t T) bool) {
+	gs.terminalOpOrderedMatch(func(od orderedData[T]) bool {
+		return match(od.data)
+	})
+}
+
+// terminalOpOrderedMatch is terminalOpMatch for operations which depend on
+// the orders of the elements.
+func (gs *genericStream[T]) terminalOpOrderedMatch(
+	match func(od orderedData[T]) bool) {
 	gs.nextReq <- struct{}{}
 	for od := range gs.nextData {
-		if !match(od.data) {
+		if !match(od) {
+			gs.cancel.cancel()
 			break
 		}
 		gs.nextReq <- struct{}{}
@@ -154,16 +351,47 @@ func (gs *genericStream[T]) terminalOpMatch(match func(t T) bool) {
 }
 
 func (gs *genericStream[T]) Parallel() Stream[T] {
+	if !gs.parallel {
+		if newGS := gs.parallelSplit(goMaxProcs); newGS != nil {
+			return newGS
+		}
+	}
+
 	gs.validateState()
 
 	if gs.parallel {
 		return gs
 	}
 
+	return gs.parallelN(goMaxProcs)
+}
+
+func (gs *genericStream[T]) ParallelN(n int) Stream[T] {
+	if n <= 0 {
+		panic(fmt.Sprintf("n must be positive: %v", n))
+	}
+
+	if !gs.parallel {
+		if newGS := gs.parallelSplit(n); newGS != nil {
+			return newGS
+		}
+	}
+
+	gs.validateState()
+
+	if gs.parallel && gs.parallelCount == n {
+		return gs
+	}
+
+	return gs.parallelN(n)
+}
+
+func (gs *genericStream[T]) parallelN(n int) *genericStream[T] {
 	newGS := newGenericStream(gs)
+	newGS.dense = gs.dense
 	newGS.parallel = true
-	newGS.parallelCount = goMaxProcs
-	newGS.terminalCloseCount = goMaxProcs
+	newGS.parallelCount = n
+	newGS.terminalCloseCount = n
 	newGS.nextReq = make(chan struct{}, gs.parallelCount)
 	newGS.nextData = make(chan orderedData[T], gs.parallelCount)
 
@@ -175,6 +403,109 @@ func (gs *genericStream[T]) Parallel() Stream[T] {
 	return newGS
 }
 
+func (gs *genericStream[T]) Unordered() Stream[T] {
+	gs.validateState()
+
+	if gs.unordered {
+		return gs
+	}
+
+	newGS := newGenericStream(gs)
+	newGS.unordered = true
+	newGS.dense = gs.dense
+
+	parallelCount := newGS.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go newGS.drain()
+	}
+
+	return newGS
+}
+
+func (gs *genericStream[T]) Sequential() Stream[T] {
+	gs.validateState()
+
+	if !gs.parallel {
+		return gs
+	}
+
+	return gs.sequential()
+}
+
+// sequential returns a new sequential stream consisting of the elements of
+// gs, which are emitted by a single goroutine in encounter order unless gs
+// is unordered. If the elements of an ordered parallel stream may arrive out
+// of encounter order, they are requested ahead to find the next one in
+// encounter order.
+func (gs *genericStream[T]) sequential() *genericStream[T] {
+	newGS := newGenericStream(gs)
+	newGS.dense = gs.dense
+	newGS.parallel = false
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	newGS.nextReq = make(chan struct{})
+	newGS.nextData = make(chan orderedData[T])
+
+	switch {
+	case gs.parallelCount == 1 || gs.unordered || !(gs.dense || gs.reordered):
+		// drain requests the elements one at a time, so they arrive in
+		// encounter order.
+		go newGS.drain()
+	case gs.dense:
+		// Elements are requested ahead to find the next ones in encounter
+		// order, so the end of the source doesn't mean the end of this
+		// stream.
+		newGS.prevDone = nil
+		newGS.reordered = false
+		go newGS.reorderDense()
+	default:
+		newGS.prevDone = nil
+		newGS.dense = true
+		newGS.reordered = false
+		go newGS.skipSparse(0)
+	}
+	return newGS
+}
+
+// inEncounterOrder returns a stream consisting of the elements of gs in
+// encounter order, emitted by a single goroutine, if gs is an ordered
+// parallel stream, for operations which depend on consecutive elements.
+// Otherwise, gs itself is returned.
+func (gs *genericStream[T]) inEncounterOrder() *genericStream[T] {
+	if gs.parallelCount == 1 || gs.unordered {
+		return gs
+	}
+
+	newGS := gs.sequential()
+	newGS.parallel = gs.parallel
+	return newGS
+}
+
+// reorderDense emits the elements of the previous parallel stream, whose
+// orders are consecutive from zero, in encounter order. Elements which
+// arrive earlier than their predecessors are held until their turn.
+func (gs *genericStream[T]) reorderDense() {
+	pending := make(map[uint64]orderedData[T])
+
+	for next := uint64(0); gs.getNextReq(); next++ {
+		data, ok := pending[next]
+		for !ok {
+			data, ok = gs.getPrevData()
+			if !ok {
+				gs.close()
+				return
+			}
+			if data.order != next {
+				pending[data.order] = data
+				ok = false
+			}
+		}
+		delete(pending, next)
+		gs.nextData <- data
+	}
+	gs.close()
+}
+
 func (gs *genericStream[T]) drain() {
 	for gs.getNextReq() {
 		data, ok := gs.getPrevData()
@@ -188,209 +519,886 @@ func (gs *genericStream[T]) drain() {
 }
 
 func (gs *genericStream[T]) Filter(predicate function.Predicate[T]) Stream[T] {
+	return fuse(gs, true, func(pull pullFunc[T]) pullFunc[T] {
+		return func() (orderedData[T], bool) {
+			for {
+				od, ok := pull()
+				if !ok || predicate(od.data) {
+					return od, ok
+				}
+			}
+		}
+	})
+}
+
+func (gs *genericStream[T]) Sample(p float64, r *rand.Rand) Stream[T] {
 	gs.validateState()
 
-	newGS := newGenericStream(gs)
+	if !(p >= 0 && p <= 1) {
+		panic(fmt.Sprintf("p must be in [0, 1]: %v", p))
+	}
+
+	// r is shared by all goroutines of a parallel stream.
+	var lock sync.Mutex
+
+	return gs.Filter(func(t T) bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return r.Float64() < p
+	})
+}
+
+func (gs *genericStream[T]) Close() {
+	gs.validateState()
+
+	panic("Not Implemented Yet")
+}
+
+func (gs *genericStream[T]) ForEach(action function.Consumer[T]) {
+	if pull := gs.takePull(); pull != nil {
+		for od, ok := pull(); ok; od, ok = pull() {
+			action(od.data)
+		}
+		return
+	}
+
+	if gs.runSplit(func(_ int, part pullFunc[T]) {
+		for od, ok := part(); ok; od, ok = part() {
+			action(od.data)
+		}
+	}) {
+		return
+	}
+
+	gs.validateState()
+
+	if !gs.parallel {
+		gs.terminalOp(action)
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		wg.Add(1)
+		go func() {
+			gs.terminalOp(action)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func (gs *genericStream[T]) ForEachOrdered(action function.Consumer[T]) {
+	if pull := gs.takePull(); pull != nil {
+		for od, ok := pull(); ok; od, ok = pull() {
+			action(od.data)
+		}
+		return
+	}
+
+	gs.validateState()
+
+	if !gs.parallel {
+		gs.terminalOp(action)
+		return
+	}
+
+	if !gs.unordered && !gs.dense {
+		for _, t := range gs.ToSlice() {
+			action(t)
+		}
+		return
+	}
+
+	var lock sync.Mutex
+	pending := make(map[uint64]T)
+	next := uint64(0)
+
+	var wg sync.WaitGroup
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		wg.Add(1)
+		go func() {
+			gs.terminalOpOrderedData(func(od orderedData[T]) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				if gs.unordered {
+					action(od.data)
+					return
+				}
+
+				// perform the action for the elements in turn
+				pending[od.order] = od.data
+				for {
+					t, ok := pending[next]
+					if !ok {
+						break
+					}
+					delete(pending, next)
+					action(t)
+					next++
+				}
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func (gs *genericStream[T]) TryForEach(action function.CheckedConsumer[T]) error {
+	if pull := gs.takePull(); pull != nil {
+		for od, ok := pull(); ok; od, ok = pull() {
+			if err := action(od.data); err != nil {
+				return err
+			}
+		}
+		return gs.Err()
+	}
+
+	gs.validateState()
+
+	var lock sync.Mutex
+	var firstErr error
+	failed := func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return firstErr != nil
+	}
+
+	var wg sync.WaitGroup
 
 	parallelCount := gs.parallelCount
 	for i := 0; i < parallelCount; i++ {
-		go newGS.filter(predicate)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			gs.terminalOpMatch(func(t T) bool {
+				if failed() {
+					return false
+				}
+
+				if err := action(t); err != nil {
+					lock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					lock.Unlock()
+					return false
+				}
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return gs.Err()
+}
+
+func (gs *genericStream[T]) Drain() {
+	gs.ForEach(func(t T) {})
+}
+
+func (gs *genericStream[T]) Sorted(cmp func(a, b T) int) Stream[T] {
+	dataSlice := gs.ToSlice()
+	slices.SortFunc(dataSlice, cmp)
+	return ofCollected(gs, dataSlice)
+}
+
+func (gs *genericStream[T]) Reverse() Stream[T] {
+	dataSlice := gs.ToSlice()
+	slices.Reverse(dataSlice)
+	return ofCollected(gs, dataSlice)
+}
+
+func (gs *genericStream[T]) Shuffle(r *rand.Rand) Stream[T] {
+	dataSlice := gs.ToSlice()
+	r.Shuffle(len(dataSlice), func(i, j int) {
+		dataSlice[i], dataSlice[j] = dataSlice[j], dataSlice[i]
+	})
+
+	newGS := ofCollected(gs, dataSlice)
+	if gs.parallel {
+		// the permutation is random, so its order need not be kept.
+		newGS.unordered = true
+		return newGS.Parallel()
 	}
 	return newGS
 }
 
-func (gs *genericStream[T]) filter(predicate function.Predicate[T]) {
+func (gs *genericStream[T]) Prepend(values ...T) Stream[T] {
+	gs.validateState()
+
+	return Concat(Of(values...), Stream[T](gs))
+}
+
+func (gs *genericStream[T]) Append(values ...T) Stream[T] {
+	gs.validateState()
+
+	return Concat(Stream[T](gs), Of(values...))
+}
+
+func (gs *genericStream[T]) Peek(action function.Consumer[T]) Stream[T] {
+	newGS := fuse(gs, true, func(pull pullFunc[T]) pullFunc[T] {
+		return func() (orderedData[T], bool) {
+			od, ok := pull()
+			if ok {
+				action(od.data)
+			}
+			return od, ok
+		}
+	})
+	newGS.dense = gs.dense
+	return newGS
+}
+
+func (gs *genericStream[T]) PeekNamed(
+	name string,
+	action function.Consumer[T],
+) Stream[T] {
+	gs.validateState()
+
+	stage := &stageStats{name: name}
+
+	newGS := gs.Peek(func(t T) {
+		action(t)
+		stage.count.Add(1)
+	}).(*genericStream[T])
+	newGS.stages = append(slices.Clip(gs.stages), stage)
+
+	return newGS
+}
+
+func (gs *genericStream[T]) Limit(maxSize int) Stream[T] {
+	if maxSize < 0 {
+		panic(fmt.Sprintf("maxSize must not be negative: %v", maxSize))
+	}
+
+	if newGS := fuse(gs, false, func(pull pullFunc[T]) pullFunc[T] {
+		count := 0
+		return func() (orderedData[T], bool) {
+			if count == maxSize {
+				return orderedData[T]{}, false
+			}
+			count++
+			return pull()
+		}
+	}); newGS != nil {
+		newGS.dense = gs.dense
+		return newGS
+	}
+
+	gs.validateState()
+
+	newGS := newGenericStream(gs)
+	newGS.dense = gs.dense
+
+	// The pipeline up to this stream is canceled once the elements are
+	// limited, while the elements of newGS are still needed downstream.
+	newGS.cancel = newCanceler(gs.cancel)
+
+	// we don't process elements in parallel to limit the
+	// number of elements.
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+
+	limit := newGS.limit
+	if gs.parallelCount > 1 && !gs.unordered && maxSize > 0 &&
+		(gs.dense || gs.reordered) {
+		// Elements are requested ahead to find the first ones in
+		// encounter order, so the end of the source doesn't mean the end
+		// of this stream. Otherwise, the elements arrive in encounter
+		// order, because limit requests them one at a time.
+		newGS.prevDone = nil
+		newGS.reordered = false
+		if gs.dense {
+			limit = newGS.limitDense
+		} else {
+			limit = newGS.limitSparse
+		}
+	}
+
+	go func() {
+		limit(maxSize)
+		gs.cancel.cancel()
+	}()
+	return newGS
+}
+
+func (gs *genericStream[T]) limit(maxSize int) {
+	if maxSize == 0 {
+		gs.close()
+		return
+	}
+
+	count := maxSize
 	for gs.getNextReq() {
-		od, ok := gs.getPrevData()
+		data, ok := gs.getPrevData()
 		if !ok {
 			gs.close()
 			return
 		}
+		gs.nextData <- data
+		count--
+		if count == 0 {
+			gs.close()
+			return
+		}
+	}
+	gs.close()
+}
 
-		for !predicate(od.data) {
-			od, ok = gs.getPrevData()
+// limitDense emits the first maxSize elements of the previous parallel stream,
+// whose orders are consecutive from zero, in encounter order. Elements which
+// arrive earlier than their predecessors are held until their turn.
+func (gs *genericStream[T]) limitDense(maxSize int) {
+	pending := make(map[uint64]orderedData[T])
+
+	for next := uint64(0); next < uint64(maxSize) && gs.getNextReq(); next++ {
+		data, ok := pending[next]
+		for !ok {
+			data, ok = gs.getPrevData()
 			if !ok {
 				gs.close()
 				return
 			}
+			if data.order != next {
+				if data.order < uint64(maxSize) {
+					pending[data.order] = data
+				}
+				ok = false
+			}
+		}
+		delete(pending, next)
+		gs.nextData <- data
+	}
+	gs.close()
+}
+
+// limitSparse emits the first maxSize elements of the previous parallel stream
+// in encounter order, whose elements may arrive out of encounter order.
+// Because some orders may be missing, all elements are requested to find the
+// first ones, which is possible because only the parts of a sized source,
+// which is finite, are served out of encounter order.
+func (gs *genericStream[T]) limitSparse(maxSize int) {
+	var ods []orderedData[T]
+	for {
+		data, ok := gs.getPrevData()
+		if !ok {
+			break
+		}
+		ods = append(ods, data)
+
+		// keep only the first maxSize elements found so far
+		if len(ods) >= 2*maxSize+goMaxProcs {
+			sortByOrder(ods)
+			ods = ods[:maxSize]
 		}
-		gs.nextData <- od
 	}
+	sortByOrder(ods)
+	ods = ods[:min(len(ods), maxSize)]
 
+	for _, data := range ods {
+		if !gs.getNextReq() {
+			break
+		}
+		gs.nextData <- data
+	}
 	gs.close()
 }
 
-func (gs *genericStream[T]) Close() {
+func (gs *genericStream[T]) Skip(n int) Stream[T] {
+	if newGS := fuse(gs, false, func(pull pullFunc[T]) pullFunc[T] {
+		remaining := n
+		return func() (orderedData[T], bool) {
+			for ; remaining > 0; remaining-- {
+				if _, ok := pull(); !ok {
+					return orderedData[T]{}, false
+				}
+			}
+
+			od, ok := pull()
+			if ok && gs.dense {
+				od.order -= uint64(n)
+			}
+			return od, ok
+		}
+	}); newGS != nil {
+		newGS.dense = gs.dense
+		return newGS
+	}
+
 	gs.validateState()
 
-	panic("Not Implemented Yet")
+	newGS := newGenericStream(gs)
+
+	// we don't process elements in parallel to limit the
+	// number of elements.
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+
+	switch {
+	case gs.parallelCount == 1 || gs.unordered || !(gs.dense || gs.reordered):
+		// skip requests the elements one at a time, so they arrive in
+		// encounter order.
+		newGS.dense = gs.dense && gs.parallelCount == 1
+		go newGS.skip(n)
+	case gs.dense:
+		newGS.dense = true
+		go newGS.skipDense(n)
+	default:
+		// Elements are requested ahead to find the first ones in encounter
+		// order, so the end of the source doesn't mean the end of this stream.
+		newGS.prevDone = nil
+		newGS.dense = true
+		newGS.reordered = false
+		go newGS.skipSparse(n)
+	}
+	return newGS
 }
 
-func (gs *genericStream[T]) ForEach(action function.Consumer[T]) {
-	gs.validateState()
+func (gs *genericStream[T]) skip(n int) {
+	remaining := n
+	for gs.getNextReq() {
+		// Skip n elements
+		for remaining > 0 {
+			_, ok := gs.getPrevData()
+			if !ok {
+				gs.close()
+				return
+			}
+			remaining--
+		}
 
-	if !gs.parallel {
-		gs.terminalOp(action)
-		return
+		data, ok := gs.getPrevData()
+		if !ok {
+			gs.close()
+			return
+		}
+		if gs.dense {
+			data.order -= uint64(n)
+		}
+		gs.nextData <- data
 	}
 
-	var wg sync.WaitGroup
+	gs.close()
+}
+
+// skipDense discards the first n elements of the previous parallel stream,
+// whose orders are consecutive from zero, in encounter order.
+func (gs *genericStream[T]) skipDense(n int) {
+	for gs.getNextReq() {
+		data, ok := gs.getPrevData()
+		for ok && data.order < uint64(n) {
+			data, ok = gs.getPrevData()
+		}
+		if !ok {
+			break
+		}
+
+		data.order -= uint64(n)
+		gs.nextData <- data
+	}
+	gs.close()
+}
+
+// skipSparse discards the first n elements of the previous parallel stream in
+// encounter order, whose elements may arrive out of encounter order.
+// Because some orders may be missing, all elements are requested to find the
+// first ones, which is possible because only the parts of a sized source,
+// which is finite, are served out of encounter order.
+func (gs *genericStream[T]) skipSparse(n int) {
+	var ods []orderedData[T]
+	for {
+		data, ok := gs.getPrevData()
+		if !ok {
+			break
+		}
+		ods = append(ods, data)
+	}
+	sortByOrder(ods)
+	ods = ods[min(len(ods), n):]
+
+	for i, data := range ods {
+		if !gs.getNextReq() {
+			break
+		}
+		data.order = uint64(i)
+		gs.nextData <- data
+	}
+	gs.close()
+}
+
+func (gs *genericStream[T]) StepBy(n int) Stream[T] {
+	if n <= 0 {
+		panic(fmt.Sprintf("n must be positive: %v", n))
+	}
+
+	if gs.parallelCount > 1 && !gs.unordered && gs.dense {
+		// The order of each element is its position in encounter order, so
+		// the elements are selected in parallel.
+		newGS := fuse(gs, true, func(pull pullFunc[T]) pullFunc[T] {
+			return func() (orderedData[T], bool) {
+				for {
+					od, ok := pull()
+					if !ok || od.order%uint64(n) == 0 {
+						od.order /= uint64(n)
+						return od, ok
+					}
+				}
+			}
+		})
+		newGS.dense = true
+		return newGS
+	}
+
+	gs.validateState()
+
+	newGS := newGenericStream(gs.inEncounterOrder())
+
+	// we don't process elements in parallel to count the
+	// number of elements.
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	go newGS.stepBy(n)
+	return newGS
+}
+
+func (gs *genericStream[T]) stepBy(n int) {
+	for gs.getNextReq() {
+		data, ok := gs.getPrevData()
+		if !ok {
+			gs.close()
+			return
+		}
+		gs.nextData <- data
 
-	parallelCount := gs.parallelCount
-	for i := 0; i < parallelCount; i++ {
-		wg.Add(1)
-		go func() {
-			gs.terminalOp(action)
-			wg.Done()
-		}()
+		// Skip n-1 elements
+		for i := 1; i < n; i++ {
+			if _, ok := gs.getPrevData(); !ok {
+				gs.close()
+				return
+			}
+		}
 	}
-	wg.Wait()
+	gs.close()
 }
 
-func (gs *genericStream[T]) Sorted(cmp func(a, b T) int) Stream[T] {
+func (gs *genericStream[T]) TakeWhile(
+	predicate function.Predicate[T],
+) Stream[T] {
 	gs.validateState()
 
-	var dataSlice []T
-
-	if !gs.parallel {
-		gs.terminalOp(func(t T) {
-			dataSlice = append(dataSlice, t)
-		})
-	} else {
-		slices := make(chan []T)
-
-		parallelCount := gs.parallelCount
-		for i := 0; i < parallelCount; i++ {
-			go func() {
-				var slice []T
-
-				gs.terminalOp(func(t T) {
-					slice = append(slice, t)
-				})
+	newGS := newGenericStream(gs.inEncounterOrder())
 
-				slices <- slice
-			}()
-		}
+	// we don't process elements in parallel to find the longest
+	// prefix.
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	go newGS.takeWhile(predicate)
+	return newGS
+}
 
-		for i := 0; i < parallelCount; i++ {
-			slice := <-slices
-			dataSlice = append(dataSlice, slice...)
+func (gs *genericStream[T]) takeWhile(predicate function.Predicate[T]) {
+	for gs.getNextReq() {
+		data, ok := gs.getPrevData()
+		if !ok || !predicate(data.data) {
+			gs.close()
+			return
 		}
+		gs.nextData <- data
 	}
-
-	slices.SortFunc(dataSlice, cmp)
-	return Of(dataSlice...)
+	gs.close()
 }
 
-func (gs *genericStream[T]) Peek(action function.Consumer[T]) Stream[T] {
+func (gs *genericStream[T]) DropWhile(
+	predicate function.Predicate[T],
+) Stream[T] {
 	gs.validateState()
 
-	newGS := newGenericStream(gs)
-
-	parallelCount := gs.parallelCount
-	for i := 0; i < parallelCount; i++ {
-		go newGS.peek(action)
-	}
+	newGS := newGenericStream(gs.inEncounterOrder())
 
+	// we don't process elements in parallel to find the longest
+	// prefix.
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	go newGS.dropWhile(predicate)
 	return newGS
 }
 
-func (gs *genericStream[T]) peek(action function.Consumer[T]) {
+func (gs *genericStream[T]) dropWhile(predicate function.Predicate[T]) {
+	dropping := true
 	for gs.getNextReq() {
-		od, ok := gs.getPrevData()
+		data, ok := gs.getPrevData()
+
+		// Drop the longest prefix
+		for ok && dropping && predicate(data.data) {
+			data, ok = gs.getPrevData()
+		}
 		if !ok {
 			gs.close()
 			return
 		}
-		action(od.data)
-		gs.nextData <- od
+		dropping = false
+
+		gs.nextData <- data
 	}
 	gs.close()
 }
 
-func (gs *genericStream[T]) Limit(maxSize int) Stream[T] {
+func (gs *genericStream[T]) Buffer(n int) Stream[T] {
 	gs.validateState()
 
-	if gs.ordered && gs.parallelCount > 1 {
-		panic("Limit doesn't support ordered parallel stream")
+	if n <= 0 {
+		panic(fmt.Sprintf("n must be positive: %v", n))
 	}
 
 	newGS := newGenericStream(gs)
-
-	// we don't process elements in parallel to limit the
-	// number of elements.
+	newGS.dense = gs.dense
 	newGS.parallelCount = 1
-	go newGS.limit(maxSize)
+	newGS.terminalCloseCount = 0
+	// Elements are requested ahead, so the end of the source doesn't mean
+	// the end of this stream.
+	newGS.prevDone = nil
+
+	go newGS.buffer(n)
 	return newGS
 }
 
-func (gs *genericStream[T]) limit(maxSize int) {
-	if maxSize < 0 {
-		panic(fmt.Sprintf("maxSize must not be negative: %v", maxSize))
-	}
-	if maxSize == 0 {
-		gs.close()
-		return
-	}
+func (gs *genericStream[T]) buffer(n int) {
+	buffer := make(chan orderedData[T], n)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			data, ok := gs.getPrevData()
+			if !ok {
+				break
+			}
+
+			select {
+			case buffer <- data:
+			case <-done:
+				close(buffer)
+				return
+			}
+		}
+		close(buffer)
+	}()
 
-	count := maxSize
 	for gs.getNextReq() {
-		data, ok := gs.getPrevData()
+		data, ok := <-buffer
 		if !ok {
-			gs.close()
-			return
+			break
 		}
 		gs.nextData <- data
-		count--
-		if count == 0 {
-			gs.close()
-			return
-		}
+	}
+
+	// wait for the goroutine above to stop requesting elements.
+	close(done)
+	for range buffer {
 	}
 	gs.close()
 }
 
-func (gs *genericStream[T]) Skip(n int) Stream[T] {
+func (gs *genericStream[T]) Debounce(d time.Duration) Stream[T] {
 	gs.validateState()
 
-	if gs.ordered && gs.parallelCount > 1 {
-		panic("Skip doesn't support ordered parallel stream")
-	}
-
 	newGS := newGenericStream(gs)
-
-	// we don't process elements in parallel to limit the
-	// number of elements.
 	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	// Elements are requested ahead, so the end of the source doesn't mean
+	// the end of this stream.
+	newGS.prevDone = nil
 
-	go newGS.skip(n)
+	go newGS.debounce(d)
 	return newGS
 }
 
-func (gs *genericStream[T]) skip(n int) {
-	for gs.getNextReq() {
-		// Skip n elements
-		for n > 0 {
-			_, ok := gs.getPrevData()
+func (gs *genericStream[T]) debounce(d time.Duration) {
+	arrivals := make(chan orderedData[T])
+	settled := make(chan orderedData[T])
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	// requests elements as soon as possible to know when they arrive.
+	go func() {
+		for {
+			data, ok := gs.getPrevData()
 			if !ok {
-				gs.close()
+				break
+			}
+
+			select {
+			case arrivals <- data:
+			case <-done:
+				close(stopped)
 				return
 			}
-			n--
 		}
+		close(arrivals)
+		close(stopped)
+	}()
 
-		data, ok := gs.getPrevData()
+	go func() {
+		arrivals := arrivals
+		var pending orderedData[T]
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		var queue []orderedData[T] // settled elements not emitted yet
+
+		for {
+			if arrivals == nil && timeout == nil && len(queue) == 0 {
+				close(settled)
+				return
+			}
+
+			var out chan orderedData[T]
+			var head orderedData[T]
+			if len(queue) > 0 {
+				out = settled
+				head = queue[0]
+			}
+
+			select {
+			case data, ok := <-arrivals:
+				if timer != nil {
+					timer.Stop()
+				}
+				if !ok {
+					// flush the pending element.
+					if timeout != nil {
+						queue = append(queue, pending)
+						timeout = nil
+					}
+					arrivals = nil
+					continue
+				}
+				pending = data
+				timer = time.NewTimer(d)
+				timeout = timer.C
+			case <-timeout:
+				queue = append(queue, pending)
+				timeout = nil
+			case out <- head:
+				queue = queue[1:]
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+
+	for gs.getNextReq() {
+		data, ok := <-settled
 		if !ok {
-			gs.close()
-			return
+			break
 		}
 		gs.nextData <- data
 	}
 
+	close(done)
+	<-stopped
 	gs.close()
 }
 
+func (gs *genericStream[T]) WithTimeout(d time.Duration) Stream[T] {
+	gs.validateState()
+
+	newGS := newGenericStream(gs)
+	newGS.parallelCount = 1
+	newGS.terminalCloseCount = 0
+	newGS.ensureFailure()
+
+	go newGS.withTimeout(d)
+	return newGS
+}
+
+func (gs *genericStream[T]) withTimeout(d time.Duration) {
+	pulls := make(chan struct{})
+	arrivals := make(chan orderedData[T], 1)
+	stopped := make(chan struct{})
+
+	// requests elements on behalf of this stream, so that waiting for them
+	// can be abandoned at the deadline.
+	go func() {
+		for range pulls {
+			data, ok := gs.getPrevData()
+			if !ok {
+				break
+			}
+			arrivals <- data
+		}
+		close(arrivals)
+		close(stopped)
+	}()
+
+	var deadline <-chan time.Time
+loop:
+	for gs.getNextReq() {
+		if deadline == nil {
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+
+		select {
+		case <-deadline:
+			gs.failure.set(ErrTimeout)
+			break loop
+		default:
+		}
+
+		pulls <- struct{}{}
+		select {
+		case data, ok := <-arrivals:
+			if !ok {
+				break loop
+			}
+			gs.nextData <- data
+		case <-deadline:
+			gs.failure.set(ErrTimeout)
+			break loop
+		}
+	}
+
+	close(pulls)
+	gs.closeAfter(stopped)
+}
+
 func (gs *genericStream[T]) ToSlice() []T {
+	if pull := gs.takePull(); pull != nil {
+		result := make([]T, 0)
+		for od, ok := pull(); ok; od, ok = pull() {
+			result = append(result, od.data)
+		}
+		return result
+	}
+
+	// the parts are contiguous, so they are concatenated in order.
+	parts := make([][]T, gs.parallelCount)
+	if gs.runSplit(func(k int, part pullFunc[T]) {
+		for od, ok := part(); ok; od, ok = part() {
+			parts[k] = append(parts[k], od.data)
+		}
+	}) {
+		result := make([]T, 0)
+		for _, part := range parts {
+			result = append(result, part...)
+		}
+		return result
+	}
+
 	gs.validateState()
 
 	results := make(chan []orderedData[T])
@@ -417,16 +1425,15 @@ func (gs *genericStream[T]) ToSlice() []T {
 	}
 	close(results)
 
-	// sort
-	slices.SortFunc(ods, func(a, b orderedData[T]) int {
-		if a.order == b.order {
-			return 0
-		}
-		if a.order < b.order {
-			return -1
+	if gs.unordered {
+		result := make([]T, len(ods))
+		for i := 0; i < len(ods); i++ {
+			result[i] = ods[i].data
 		}
-		return 1
-	})
+		return result
+	}
+
+	sortByOrder(ods)
 
 	// copy sorted result to []T
 	result := make([]T, len(ods))
@@ -437,6 +1444,59 @@ func (gs *genericStream[T]) ToSlice() []T {
 	return result
 }
 
+func (gs *genericStream[T]) ToSortedSlice(cmp func(a, b T) int) []T {
+	gs.validateState()
+
+	compare := func(a, b orderedData[T]) int {
+		if c := cmp(a.data, b.data); c != 0 {
+			return c
+		}
+		return compareOrder(a, b)
+	}
+
+	results := make(chan []orderedData[T])
+
+	// collect and sort in parallel
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			var ods []orderedData[T]
+
+			gs.terminalOpOrderedData(func(od orderedData[T]) {
+				ods = append(ods, od)
+			})
+			slices.SortFunc(ods, compare)
+
+			results <- ods
+		}()
+	}
+
+	chunks := make([][]orderedData[T], parallelCount)
+	for i := 0; i < parallelCount; i++ {
+		chunks[i] = <-results
+	}
+	close(results)
+
+	// merge sorted chunks in pairs
+	for len(chunks) > 1 {
+		merged := chunks[:0]
+		for i := 0; i < len(chunks); i += 2 {
+			if i+1 == len(chunks) {
+				merged = append(merged, chunks[i])
+				break
+			}
+			merged = append(merged, mergeSortedData(chunks[i], chunks[i+1], compare))
+		}
+		chunks = merged
+	}
+
+	result := make([]T, len(chunks[0]))
+	for i, od := range chunks[0] {
+		result[i] = od.data
+	}
+	return result
+}
+
 func (gs *genericStream[T]) Reduce(
 	identity T,
 	accumulator function.BinaryOperator[T],
@@ -621,6 +1681,27 @@ func (gs *genericStream[T]) Max(less Less[T]) *Optional[T] {
 }
 
 func (gs *genericStream[T]) Count() int {
+	if pull := gs.takePull(); pull != nil {
+		count := 0
+		for _, ok := pull(); ok; _, ok = pull() {
+			count++
+		}
+		return count
+	}
+
+	counts := make([]int, gs.parallelCount)
+	if gs.runSplit(func(k int, part pullFunc[T]) {
+		for _, ok := part(); ok; _, ok = part() {
+			counts[k]++
+		}
+	}) {
+		count := 0
+		for _, c := range counts {
+			count += c
+		}
+		return count
+	}
+
 	gs.validateState()
 
 	results := make(chan int)
@@ -642,6 +1723,36 @@ func (gs *genericStream[T]) Count() int {
 	return count
 }
 
+func (gs *genericStream[T]) Tally(predicates ...function.Predicate[T]) []int {
+	gs.validateState()
+
+	results := make(chan []int)
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			counts := make([]int, len(predicates))
+			gs.terminalOp(func(t T) {
+				for i, predicate := range predicates {
+					if predicate(t) {
+						counts[i]++
+					}
+				}
+			})
+			results <- counts
+		}()
+	}
+
+	counts := make([]int, len(predicates))
+	for i := 0; i < parallelCount; i++ {
+		for j, count := range <-results {
+			counts[j] += count
+		}
+	}
+
+	return counts
+}
+
 func (gs *genericStream[T]) AnyMatch(predicate function.Predicate[T]) bool {
 	gs.validateState()
 
@@ -736,6 +1847,13 @@ func (gs *genericStream[T]) NoneMatch(predicate function.Predicate[T]) bool {
 }
 
 func (gs *genericStream[T]) FindFirst() *Optional[T] {
+	if pull := gs.takeOrderedPull(); pull != nil {
+		if od, ok := pull(); ok {
+			return OptionalOf(od.data)
+		}
+		return OptionalEmpty[T]()
+	}
+
 	gs.validateState()
 
 	// We don't process in parallel.
@@ -758,6 +1876,84 @@ func (gs *genericStream[T]) FindFirst() *Optional[T] {
 	return OptionalEmpty[T]()
 }
 
+func (gs *genericStream[T]) Single() (*Optional[T], error) {
+	gs.validateState()
+
+	// We don't process in parallel.
+	gs.terminalCloseCount = 1
+
+	count := 0
+	var result T
+
+	gs.terminalOpMatch(func(t T) bool {
+		count++
+		result = t
+
+		return count < 2
+	})
+
+	switch count {
+	case 0:
+		return OptionalEmpty[T](), ErrNoElements
+	case 1:
+		return OptionalOf(result), nil
+	}
+	return OptionalEmpty[T](), ErrMultipleElements
+}
+
+func (gs *genericStream[T]) ElementAt(n int) *Optional[T] {
+	if n < 0 {
+		panic(fmt.Sprintf("n must not be negative: %v", n))
+	}
+
+	if pull := gs.takeOrderedPull(); pull != nil {
+		for i := 0; ; i++ {
+			od, ok := pull()
+			if !ok {
+				return OptionalEmpty[T]()
+			}
+			if i == n {
+				return OptionalOf(od.data)
+			}
+		}
+	}
+
+	gs.validateState()
+
+	if gs.parallelCount > 1 && !gs.unordered && !gs.dense && gs.reordered {
+		// Some orders may be missing, so the position of an element is
+		// known only after the elements are put in encounter order.
+		gs = gs.sequential()
+	}
+
+	// The order of each element of a dense parallel stream is its position,
+	// and otherwise the elements arrive in encounter order, because they
+	// are requested one at a time.
+	byOrder := gs.parallelCount > 1 && !gs.unordered && gs.dense
+
+	// We don't process in parallel.
+	gs.terminalCloseCount = 1
+
+	foundAny := false
+	var result T
+	position := 0
+
+	gs.terminalOpOrderedMatch(func(od orderedData[T]) bool {
+		if byOrder && od.order == uint64(n) || !byOrder && position == n {
+			foundAny = true
+			result = od.data
+			return false
+		}
+		position++
+		return true
+	})
+
+	if foundAny {
+		return OptionalOf(result)
+	}
+	return OptionalEmpty[T]()
+}
+
 func (gs *genericStream[T]) FindAny() *Optional[T] {
 	gs.validateState()
 
@@ -0,0 +1,98 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog is the intermediate accumulation type of
+// ApproxDistinctCollector, which is a cardinality estimator (HyperLogLog by
+// Flajolet et al.) with 2^precision registers. Two HyperLogLogs with the same
+// precision can be merged, which makes it suitable for parallel streams.
+type HyperLogLog struct {
+	precision int
+	registers []uint8
+}
+
+func newHyperLogLog(precision int) *HyperLogLog {
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// add adds a 64-bit hash value of an element.
+func (h *HyperLogLog) add(hash uint64) {
+	index := hash >> (64 - h.precision)
+	// the guard bit bounds the rank when the remaining bits are all zero.
+	w := hash<<h.precision | 1<<(h.precision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+	h.registers[index] = max(h.registers[index], rank)
+}
+
+// merge merges other into h.
+func (h *HyperLogLog) merge(other *HyperLogLog) {
+	for i, rank := range other.registers {
+		h.registers[i] = max(h.registers[i], rank)
+	}
+}
+
+// estimate returns the estimated number of distinct elements.
+func (h *HyperLogLog) estimate() int64 {
+	m := float64(len(h.registers))
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range h.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// small range correction by linear counting
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(e))
+}
+
+// hasher returns a function which computes a 64-bit hash value of an
+// element with the seed. Strings and integers are hashed directly, and
+// other types are hashed by their Go-syntax representation.
+func hasher[T comparable](seed maphash.Seed) func(t T) uint64 {
+	return func(t T) uint64 {
+		var buf [8]byte
+		switch v := any(t).(type) {
+		case string:
+			return maphash.String(seed, v)
+		case int:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		case int64:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		case uint64:
+			binary.LittleEndian.PutUint64(buf[:], v)
+		default:
+			return maphash.Bytes(seed, fmt.Appendf(nil, "%#v", t))
+		}
+		return maphash.Bytes(seed, buf[:])
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import "fmt"
+
+// Indexed is an element of a stream paired with its index in encounter
+// order.
+type Indexed[T any] struct {
+	Index int64
+	Value T
+}
+
+// String returns a string representation of this Indexed suitable for
+// debugging.
+func (i Indexed[T]) String() string {
+	return fmt.Sprintf("%d:%v", i.Index, i.Value)
+}
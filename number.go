@@ -1,7 +1,155 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
+import "math"
+
+// Number is a constraint that permits any integer or floating-point type.
 type Number interface {
 	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr | ~float32 | ~float64
 }
+
+// isFloat reports whether T is a floating-point type.
+func isFloat[T Number]() bool {
+	// 1/2 is truncated to zero for integer types.
+	return T(1)/2 != 0
+}
+
+// compensatedSum is a sum of float64 values using Kahan summation, which
+// reduces the error accumulated by adding a large number of values.
+type compensatedSum struct {
+	sum          float64
+	compensation float64 // the negated low-order bits lost from sum
+	simpleSum    float64 // to recover infinities
+}
+
+func (s *compensatedSum) add(value float64) {
+	s.addWithCompensation(value)
+	s.simpleSum += value
+}
+
+func (s *compensatedSum) addWithCompensation(value float64) {
+	y := value - s.compensation
+	t := s.sum + y
+	s.compensation = (t - s.sum) - y
+	s.sum = t
+}
+
+func (s *compensatedSum) combine(other *compensatedSum) {
+	s.addWithCompensation(other.sum)
+	s.addWithCompensation(-other.compensation)
+	s.simpleSum += other.simpleSum
+}
+
+func (s *compensatedSum) value() float64 {
+	// If the compensated sum is spuriously NaN from accumulating one or more
+	// same-signed infinite values, return the correctly-signed infinity
+	// stored in the simple sum.
+	v := s.sum - s.compensation
+	if math.IsNaN(v) && math.IsInf(s.simpleSum, 0) {
+		return s.simpleSum
+	}
+	return v
+}
+
+// Welford is the intermediate accumulation type of VarianceCollector and
+// StdDevCollector, which holds the count, mean and sum of squared deviations
+// from the mean of float64 values, updated by Welford's online algorithm,
+// which is numerically stable unlike the naive sum of squares.
+type Welford struct {
+	count int64
+	mean  float64
+	m2    float64
+}
+
+func (w *Welford) add(value float64) {
+	w.count++
+	delta := value - w.mean
+	w.mean += delta / float64(w.count)
+	w.m2 += delta * (value - w.mean)
+}
+
+// combine combines other into w, using the parallel algorithm by Chan et al.
+func (w *Welford) combine(other *Welford) {
+	if other.count == 0 {
+		return
+	}
+	if w.count == 0 {
+		*w = *other
+		return
+	}
+
+	count := w.count + other.count
+	delta := other.mean - w.mean
+	w.mean += delta * float64(other.count) / float64(count)
+	w.m2 += other.m2 +
+		delta*delta*float64(w.count)*float64(other.count)/float64(count)
+	w.count = count
+}
+
+// variance returns the population variance, or 0 if no values are added.
+func (w *Welford) variance() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return w.m2 / float64(w.count)
+}
+
+// MomentsAccumulator is the intermediate accumulation type of
+// MomentsCollector, which holds the count, mean and sums of the second, third
+// and fourth powers of deviations from the mean of float64 values, updated in
+// one pass by the online algorithm by Terriberry, and combined by the
+// formulas by Pébay.
+type MomentsAccumulator struct {
+	count int64
+	mean  float64
+	m2    float64
+	m3    float64
+	m4    float64
+}
+
+func (m *MomentsAccumulator) add(value float64) {
+	n1 := float64(m.count)
+	m.count++
+	n := float64(m.count)
+
+	delta := value - m.mean
+	deltaN := delta / n
+	deltaN2 := deltaN * deltaN
+	term1 := delta * deltaN * n1
+
+	m.mean += deltaN
+	m.m4 += term1*deltaN2*(n*n-3*n+3) + 6*deltaN2*m.m2 - 4*deltaN*m.m3
+	m.m3 += term1*deltaN*(n-2) - 3*deltaN*m.m2
+	m.m2 += term1
+}
+
+func (m *MomentsAccumulator) combine(other *MomentsAccumulator) {
+	if other.count == 0 {
+		return
+	}
+	if m.count == 0 {
+		*m = *other
+		return
+	}
+
+	na, nb := float64(m.count), float64(other.count)
+	n := na + nb
+	delta := other.mean - m.mean
+	delta2 := delta * delta
+	delta3 := delta2 * delta
+	delta4 := delta2 * delta2
+
+	m4 := m.m4 + other.m4 +
+		delta4*na*nb*(na*na-na*nb+nb*nb)/(n*n*n) +
+		6*delta2*(na*na*other.m2+nb*nb*m.m2)/(n*n) +
+		4*delta*(na*other.m3-nb*m.m3)/n
+	m3 := m.m3 + other.m3 +
+		delta3*na*nb*(na-nb)/(n*n) +
+		3*delta*(na*other.m2-nb*m.m2)/n
+	m2 := m.m2 + other.m2 + delta2*na*nb/n
+
+	m.count += other.count
+	m.mean += delta * nb / n
+	m.m2, m.m3, m.m4 = m2, m3, m4
+}
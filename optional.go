@@ -1,4 +1,4 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
@@ -68,6 +68,16 @@ func (o *Optional[T]) Filter(predicate function.Predicate[T]) *Optional[T] {
 	return &Optional[T]{} // empty
 }
 
+// MapSame returns an Optional describing the result of applying the given
+// mapping function to a value if the value is present, otherwise returns an
+// empty Optional. Use OptionalMap function to map a value into another type.
+func (o *Optional[T]) MapSame(mapper function.UnaryOperator[T]) *Optional[T] {
+	if !o.present {
+		return o // o is empty
+	}
+	return OptionalOf(mapper(o.value))
+}
+
 // Or returns an Optional describing a value if the value is present,
 // otherwise returns an Optional produced by the supplying function.
 func (o *Optional[T]) Or(supplier function.Supplier[*Optional[T]]) *Optional[T] {
@@ -115,6 +125,25 @@ func (o *Optional[T]) OrElsePanic() T {
 	panic("no value is present")
 }
 
+// Equal reports whether this Optional and other are equal: both are empty,
+// or both have values which are equal according to eq.
+func (o *Optional[T]) Equal(other *Optional[T], eq func(a, b T) bool) bool {
+	if o.present != other.present {
+		return false
+	}
+	return !o.present || eq(o.value, other.value)
+}
+
+// Ptr returns a pointer to a copy of a value if the value is present,
+// otherwise returns nil.
+func (o *Optional[T]) Ptr() *T {
+	if o.present {
+		v := o.value
+		return &v
+	}
+	return nil
+}
+
 // String returns a non-empty string representation of this Optional
 // suitable for debugging. The exact presentation format is unspecified
 // and mya vary between implementations and versions.
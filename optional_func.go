@@ -1,4 +1,4 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
@@ -12,12 +12,27 @@ func OptionalOf[T any](value T) *Optional[T] {
 	}
 }
 
+// OptionalOfPointer returns an Optional describing the value pointed to by p
+// if p is not nil, otherwise returns an empty Optional.
+func OptionalOfPointer[T any](p *T) *Optional[T] {
+	if p == nil {
+		return &Optional[T]{} // empty
+	}
+	return OptionalOf(*p)
+}
+
 // OptionalEmtpy returns an empty Optional instance. No value is present for
 // this Optional.
 func OptionalEmpty[T any]() *Optional[T] {
 	return &Optional[T]{}
 }
 
+// OptionalEqual reports whether o1 and o2 are equal: both are empty, or both
+// have equal values.
+func OptionalEqual[T comparable](o1, o2 *Optional[T]) bool {
+	return o1.Equal(o2, func(a, b T) bool { return a == b })
+}
+
 // OptionalMap returns the result applying the give mapping function to a value
 // if the value is present, otherwise returns an empty Optional
 func OptionalMap[U, T any](
@@ -48,3 +63,94 @@ func OptionalFlatMap[U, T any](
 	}
 	return r
 }
+
+// OptionalZip returns the result of applying the given function to values of
+// a and b if both values are present, otherwise returns an empty Optional.
+func OptionalZip[A, B, R any](
+	a *Optional[A],
+	b *Optional[B],
+	f func(a A, b B) R,
+) *Optional[R] {
+	if !a.IsPresent() || !b.IsPresent() {
+		return &Optional[R]{} // empty
+	}
+	return OptionalOf(f(a.value, b.value))
+}
+
+// CollectPresent returns a slice of the values of the Optionals in optionals
+// whose values are present, skipping empty ones.
+func CollectPresent[T any](optionals []*Optional[T]) []T {
+	var result []T
+	for _, o := range optionals {
+		if o.IsPresent() {
+			result = append(result, o.value)
+		}
+	}
+	return result
+}
+
+// OptionalSequence returns an Optional describing a slice of the values of
+// the Optionals in optionals if all values are present, otherwise returns an
+// empty Optional.
+func OptionalSequence[T any](optionals []*Optional[T]) *Optional[[]T] {
+	result := make([]T, 0, len(optionals))
+	for _, o := range optionals {
+		if !o.IsPresent() {
+			return &Optional[[]T]{} // empty
+		}
+		result = append(result, o.value)
+	}
+	return OptionalOf(result)
+}
+
+// OptionalSequenceStream is the same as OptionalSequence, but combines the
+// Optionals of the given stream in encounter order. The rest of the stream
+// is not consumed once an empty Optional is found, so the stream may be
+// infinite if it contains an empty Optional.
+func OptionalSequenceStream[T any](stream Stream[*Optional[T]]) *Optional[[]T] {
+	empty := false
+	optionals := stream.TakeWhile(func(o *Optional[T]) bool {
+		empty = !o.IsPresent()
+		return !empty
+	}).ToSlice()
+	if empty {
+		return &Optional[[]T]{} // empty
+	}
+	return OptionalSequence(optionals)
+}
+
+// OptionalEmptyFirst returns a Comparator of Optionals which considers an
+// empty (or nil) Optional to be less than a present one, and compares the
+// values by c if both are present.
+func OptionalEmptyFirst[T any](c function.Comparator[T]) function.Comparator[*Optional[T]] {
+	return optionalComparator(c, true)
+}
+
+// OptionalEmptyLast returns a Comparator of Optionals which considers an
+// empty (or nil) Optional to be greater than a present one, and compares the
+// values by c if both are present.
+func OptionalEmptyLast[T any](c function.Comparator[T]) function.Comparator[*Optional[T]] {
+	return optionalComparator(c, false)
+}
+
+func optionalComparator[T any](
+	c function.Comparator[T],
+	emptyFirst bool,
+) function.Comparator[*Optional[T]] {
+	valuePointer := func(o *Optional[T]) *T {
+		if o == nil || !o.present {
+			return nil
+		}
+		return &o.value
+	}
+
+	var pc function.Comparator[*T]
+	if emptyFirst {
+		pc = function.NilFirst(c)
+	} else {
+		pc = function.NilLast(c)
+	}
+	return func(a, b *Optional[T]) int {
+		return pc(valuePointer(a), valuePointer(b))
+	}
+}
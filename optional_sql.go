@@ -0,0 +1,79 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Scan implements the sql.Scanner interface, so that a nullable column can
+// be scanned into an Optional: NULL is scanned as an empty Optional. Values
+// are converted as by sql.NullString, sql.NullInt64, sql.NullFloat64,
+// sql.NullBool and sql.NullTime when T is string, int64, float64, bool and
+// time.Time respectively. For other types, the value must be of type T. A
+// []byte value is copied, because it is owned by the driver.
+func (o *Optional[T]) Scan(src any) error {
+	if src == nil {
+		*o = Optional[T]{} // empty
+		return nil
+	}
+
+	var scanned any
+	var err error
+	switch any(o.value).(type) {
+	case string:
+		var n sql.NullString
+		err = n.Scan(src)
+		scanned = n.String
+	case int64:
+		var n sql.NullInt64
+		err = n.Scan(src)
+		scanned = n.Int64
+	case float64:
+		var n sql.NullFloat64
+		err = n.Scan(src)
+		scanned = n.Float64
+	case bool:
+		var n sql.NullBool
+		err = n.Scan(src)
+		scanned = n.Bool
+	case time.Time:
+		var n sql.NullTime
+		err = n.Scan(src)
+		scanned = n.Time
+	default:
+		if b, ok := src.([]byte); ok {
+			src = bytes.Clone(b)
+		}
+		scanned = src
+	}
+	if err != nil {
+		return err
+	}
+
+	value, ok := scanned.(T)
+	if !ok {
+		return fmt.Errorf("unsupported Scan, storing %T into %T", src, o)
+	}
+	*o = Optional[T]{value: value, present: true}
+	return nil
+}
+
+// Value implements the driver.Valuer interface: an empty Optional is NULL,
+// and a value is converted by driver.DefaultParameterConverter unless it
+// implements driver.Valuer. As with sql.NullString, Value has a value
+// receiver, so that both an Optional and a pointer to it are a driver.Valuer,
+// and a nil pointer is NULL.
+func (o Optional[T]) Value() (driver.Value, error) {
+	if !o.present {
+		return nil, nil
+	}
+	if valuer, ok := any(o.value).(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(o.value)
+}
@@ -0,0 +1,294 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YoshikiShibata/gostream/function"
+)
+
+func TestOptional_OfPointer(t *testing.T) {
+	v := 10
+	o := OptionalOfPointer(&v)
+	if !o.IsPresent() || o.Get() != 10 {
+		t.Errorf("o is %v, want Optional[10]", o)
+	}
+
+	// the value is copied
+	v = 20
+	if o.Get() != 10 {
+		t.Errorf("o.Get() is %d, want 10", o.Get())
+	}
+
+	o = OptionalOfPointer[int](nil)
+	if o.IsPresent() {
+		t.Errorf("o is %v, want empty", o)
+	}
+}
+
+func TestOptional_Ptr(t *testing.T) {
+	o := OptionalOf(10)
+	p := o.Ptr()
+	if p == nil || *p != 10 {
+		t.Fatalf("o.Ptr() is %v, want pointer to 10", p)
+	}
+
+	// the value is copied
+	*p = 20
+	if o.Get() != 10 {
+		t.Errorf("o.Get() is %d, want 10", o.Get())
+	}
+
+	if p := OptionalEmpty[int]().Ptr(); p != nil {
+		t.Errorf("OptionalEmpty().Ptr() is %v, want nil", p)
+	}
+}
+
+func TestOptional_Equal(t *testing.T) {
+	for _, tc := range [...]struct {
+		o1, o2 *Optional[string]
+		want   bool
+	}{
+		{o1: OptionalEmpty[string](), o2: OptionalEmpty[string](), want: true},
+		{o1: OptionalOf("go"), o2: OptionalEmpty[string](), want: false},
+		{o1: OptionalEmpty[string](), o2: OptionalOf("go"), want: false},
+		{o1: OptionalOf("go"), o2: OptionalOf("go"), want: true},
+		{o1: OptionalOf("go"), o2: OptionalOf("Go"), want: false},
+	} {
+		if got := OptionalEqual(tc.o1, tc.o2); got != tc.want {
+			t.Errorf("OptionalEqual(%v, %v) is %v, want %v",
+				tc.o1, tc.o2, got, tc.want)
+		}
+	}
+
+	if !OptionalOf("go").Equal(OptionalOf("Go"), strings.EqualFold) {
+		t.Errorf("Equal with strings.EqualFold is false, want true")
+	}
+}
+
+func TestOptional_Zip(t *testing.T) {
+	repeat := func(s string, n int) string { return strings.Repeat(s, n) }
+
+	for _, tc := range [...]struct {
+		a    *Optional[string]
+		b    *Optional[int]
+		want *Optional[string]
+	}{
+		{a: OptionalOf("go"), b: OptionalOf(3), want: OptionalOf("gogogo")},
+		{a: OptionalOf("go"), b: OptionalEmpty[int](), want: OptionalEmpty[string]()},
+		{a: OptionalEmpty[string](), b: OptionalOf(3), want: OptionalEmpty[string]()},
+		{a: OptionalEmpty[string](), b: OptionalEmpty[int](), want: OptionalEmpty[string]()},
+	} {
+		result := OptionalZip(tc.a, tc.b, repeat)
+		if !OptionalEqual(result, tc.want) {
+			t.Errorf("OptionalZip(%v, %v) is %v, want %v",
+				tc.a, tc.b, result, tc.want)
+		}
+	}
+}
+
+func TestOptional_MapSame(t *testing.T) {
+	result := OptionalOf("  Go ").MapSame(strings.TrimSpace).MapSame(strings.ToLower)
+	if !OptionalEqual(result, OptionalOf("go")) {
+		t.Errorf("result is %v, want Optional[go]", result)
+	}
+
+	result = OptionalEmpty[string]().MapSame(strings.TrimSpace)
+	if result.IsPresent() {
+		t.Errorf("result is %v, want empty", result)
+	}
+}
+
+func TestOptional_CollectPresent(t *testing.T) {
+	optionals := []*Optional[int]{
+		OptionalOf(1), OptionalEmpty[int](), OptionalOf(2),
+	}
+	result := CollectPresent(optionals)
+	if want := []int{1, 2}; !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+
+	if result := CollectPresent[int](nil); len(result) != 0 {
+		t.Errorf("result is %v, want empty", result)
+	}
+}
+
+func TestOptional_Sequence(t *testing.T) {
+	result := OptionalSequence([]*Optional[int]{OptionalOf(1), OptionalOf(2)})
+	if !result.IsPresent() || !slices.Equal(result.Get(), []int{1, 2}) {
+		t.Errorf("result is %v, want Optional[[1 2]]", result)
+	}
+
+	result = OptionalSequence([]*Optional[int]{OptionalOf(1), OptionalEmpty[int]()})
+	if result.IsPresent() {
+		t.Errorf("result is %v, want empty", result)
+	}
+
+	result = OptionalSequence[int](nil)
+	if !result.IsPresent() || len(result.Get()) != 0 {
+		t.Errorf("result is %v, want Optional[[]]", result)
+	}
+}
+
+func TestOptional_SequenceStream(t *testing.T) {
+	present := func(i int) *Optional[int] { return OptionalOf(i) }
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Map(Range(0, 100), present)
+		if parallel {
+			s = s.Parallel()
+		}
+		result := OptionalSequenceStream(s)
+		if !result.IsPresent() || !slices.Equal(result.Get(), Range(0, 100).ToSlice()) {
+			t.Errorf("[%v] result is %v, want Optional[[0 ... 99]]", parallel, result)
+		}
+	}
+
+	emptyAt50 := func(i int) *Optional[int] {
+		if i == 50 {
+			return OptionalEmpty[int]()
+		}
+		return OptionalOf(i)
+	}
+	for _, parallel := range [...]bool{false, true} {
+		s := Map(Range(0, 100), emptyAt50)
+		if parallel {
+			s = s.Parallel()
+		}
+		if result := OptionalSequenceStream(s); result.IsPresent() {
+			t.Errorf("[%v] result is %v, want empty", parallel, result)
+		}
+	}
+
+	// an infinite stream ends at the first empty Optional
+	inc := func(i int) int { return i + 1 }
+	result := OptionalSequenceStream(Map(Iterate(0, inc), emptyAt50))
+	if result.IsPresent() {
+		t.Errorf("result is %v, want empty", result)
+	}
+
+	result = OptionalSequenceStream(Empty[*Optional[int]]())
+	if !result.IsPresent() || len(result.Get()) != 0 {
+		t.Errorf("result is %v, want Optional[[]]", result)
+	}
+}
+
+func TestOptional_Scan(t *testing.T) {
+	var _ sql.Scanner = &Optional[string]{}
+
+	var s Optional[string]
+	if err := s.Scan([]byte("go")); err != nil || !OptionalEqual(&s, OptionalOf("go")) {
+		t.Errorf("s is %v, %v, want Optional[go]", &s, err)
+	}
+	if err := s.Scan(nil); err != nil || s.IsPresent() {
+		t.Errorf("s is %v, %v, want empty", &s, err)
+	}
+
+	var i Optional[int64]
+	if err := i.Scan("42"); err != nil || !OptionalEqual(&i, OptionalOf[int64](42)) {
+		t.Errorf("i is %v, %v, want Optional[42]", &i, err)
+	}
+	if err := i.Scan("x"); err == nil {
+		t.Errorf("i.Scan(x) returns nil error")
+	}
+
+	var f Optional[float64]
+	if err := f.Scan(int64(2)); err != nil || !OptionalEqual(&f, OptionalOf(2.0)) {
+		t.Errorf("f is %v, %v, want Optional[2]", &f, err)
+	}
+
+	var b Optional[bool]
+	if err := b.Scan(int64(1)); err != nil || !OptionalEqual(&b, OptionalOf(true)) {
+		t.Errorf("b is %v, %v, want Optional[true]", &b, err)
+	}
+
+	now := time.Now()
+	var tm Optional[time.Time]
+	if err := tm.Scan(now); err != nil || !tm.IsPresent() || !tm.Get().Equal(now) {
+		t.Errorf("tm is %v, %v, want Optional[%v]", &tm, err, now)
+	}
+
+	src := []byte("go")
+	var bs Optional[[]byte]
+	if err := bs.Scan(src); err != nil || string(bs.Get()) != "go" {
+		t.Errorf("bs is %v, %v, want Optional[go]", &bs, err)
+	}
+	src[0] = 'n' // the driver may reuse src
+	if got := string(bs.Get()); got != "go" {
+		t.Errorf("bs is Optional[%v] after src is modified, want Optional[go]", got)
+	}
+
+	var u Optional[uint8]
+	if err := u.Scan("x"); err == nil {
+		t.Errorf("u.Scan(x) returns nil error")
+	}
+}
+
+func TestOptional_Value(t *testing.T) {
+	var _ driver.Valuer = Optional[string]{}
+	var _ driver.Valuer = &Optional[string]{}
+
+	for _, tc := range [...]struct {
+		valuer driver.Valuer
+		want   driver.Value
+	}{
+		{valuer: OptionalEmpty[string](), want: nil},
+		{valuer: OptionalOf("go"), want: "go"},
+		{valuer: OptionalOf(42), want: int64(42)},
+		{valuer: OptionalOf(true), want: true},
+		{valuer: OptionalOf(sql.NullInt64{}), want: nil},
+	} {
+		value, err := tc.valuer.Value()
+		if err != nil || value != tc.want {
+			t.Errorf("%v.Value() is %v, %v, want %v", tc.valuer, value, err, tc.want)
+		}
+	}
+
+	// a nil pointer is converted to NULL as sql.NullString is.
+	value, err := driver.DefaultParameterConverter.ConvertValue((*Optional[string])(nil))
+	if err != nil || value != nil {
+		t.Errorf("nil is converted to %v, %v, want nil", value, err)
+	}
+}
+
+func TestOptional_EmptyFirstLast(t *testing.T) {
+	optionals := []*Optional[int]{
+		OptionalOf(3), OptionalEmpty[int](), OptionalOf(1), nil, OptionalOf(2),
+	}
+	values := func(optionals []*Optional[int]) []string {
+		result := make([]string, 0, len(optionals))
+		for _, o := range optionals {
+			if o == nil || !o.IsPresent() {
+				result = append(result, "-")
+			} else {
+				result = append(result, strconv.Itoa(o.Get()))
+			}
+		}
+		return result
+	}
+
+	for _, tc := range [...]struct {
+		name string
+		cmp  function.Comparator[*Optional[int]]
+		want []string
+	}{
+		{"EmptyFirst", OptionalEmptyFirst(function.NaturalOrder[int]()),
+			[]string{"-", "-", "1", "2", "3"}},
+		{"EmptyLast", OptionalEmptyLast(function.NaturalOrder[int]()),
+			[]string{"1", "2", "3", "-", "-"}},
+		{"EmptyLastReversed", OptionalEmptyLast(function.NaturalOrder[int]()).Reversed(),
+			[]string{"-", "-", "3", "2", "1"}},
+	} {
+		result := values(Of(optionals...).Sorted(tc.cmp).ToSlice())
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("[%s] result is %v, want %v", tc.name, result, tc.want)
+		}
+	}
+}
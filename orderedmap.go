@@ -0,0 +1,89 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Entry is a key-value pair.
+type Entry[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// OrderedMap is a map which preserves the order of first insertion of keys.
+type OrderedMap[K comparable, V any] struct {
+	entries []Entry[K, V]
+	index   map[K]int
+}
+
+func newOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		index: make(map[K]int),
+	}
+}
+
+// put associates value with key. If key is already present, the value is
+// replaced while keeping the position of key.
+func (m *OrderedMap[K, V]) put(key K, value V) {
+	if i, ok := m.index[key]; ok {
+		m.entries[i].Value = value
+		return
+	}
+	m.index[key] = len(m.entries)
+	m.entries = append(m.entries, Entry[K, V]{Key: key, Value: value})
+}
+
+// Get returns the value associated with key, and whether key is present.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if i, ok := m.index[key]; ok {
+		return m.entries[i].Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Len returns the number of entries.
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+// Keys returns the keys in insertion order.
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, len(m.entries))
+	for i, e := range m.entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Values returns the values in insertion order of their keys.
+func (m *OrderedMap[K, V]) Values() []V {
+	values := make([]V, len(m.entries))
+	for i, e := range m.entries {
+		values[i] = e.Value
+	}
+	return values
+}
+
+// Entries returns the entries in insertion order.
+func (m *OrderedMap[K, V]) Entries() []Entry[K, V] {
+	return append([]Entry[K, V](nil), m.entries...)
+}
+
+// String returns a string representation of the map in insertion order, in
+// the same form as fmt prints a map, such as "OrderedMap[b:2 a:1]".
+func (m *OrderedMap[K, V]) String() string {
+	var b strings.Builder
+	b.WriteString("OrderedMap[")
+	for i, e := range m.entries {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%v:%v", e.Key, e.Value)
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,21 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import "fmt"
+
+// Pair is a pair of values of possibly different types.
+type Pair[T1, T2 any] struct {
+	First  T1
+	Second T2
+}
+
+// PairOf returns a Pair of first and second.
+func PairOf[T1, T2 any](first T1, second T2) Pair[T1, T2] {
+	return Pair[T1, T2]{First: first, Second: second}
+}
+
+// String returns a string representation of the pair, such as "(1, one)".
+func (p Pair[T1, T2]) String() string {
+	return fmt.Sprintf("(%v, %v)", p.First, p.Second)
+}
@@ -0,0 +1,355 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import "sync"
+
+// pullFunc returns the next element of a pull-based stream, or false if no
+// more elements remain. Once it returns false, it keeps returning false.
+type pullFunc[T any] func() (orderedData[T], bool)
+
+// newSizedStream returns a sequential stream of count elements, the i-th of
+// which is computed by at(i). The stream is pull-based, and split into
+// contiguous parts if it is made parallel.
+func newSizedStream[T any](count uint64, at func(i uint64) T) *genericStream[T] {
+	part := func(lo, hi uint64) pullFunc[T] {
+		i := lo
+		return func() (orderedData[T], bool) {
+			if i == hi {
+				return orderedData[T]{}, false
+			}
+			od := orderedData[T]{order: i, data: at(i)}
+			i++
+			return od, true
+		}
+	}
+
+	return &genericStream[T]{
+		parallelCount: 1,
+		dense:         true,
+		cancel:        newCanceler(),
+		prevDone:      make(chan struct{}),
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[T]),
+		pull:          part(0, count),
+		split: func(n int) []pullFunc[T] {
+			// the first count%n parts have one more element than the rest.
+			size, rest := count/uint64(n), count%uint64(n)
+			parts := make([]pullFunc[T], n)
+			lo := uint64(0)
+			for k := range parts {
+				hi := lo + size
+				if uint64(k) < rest {
+					hi++
+				}
+				parts[k] = part(lo, hi)
+				lo = hi
+			}
+			return parts
+		},
+	}
+}
+
+// fuse returns a new stream which applies stage to the elements of gs,
+// fused into the pipeline of gs. If gs is pull-based, split-based or has
+// fused stages, gs is closed. If gs is channel-based, the goroutines of the
+// new stream pull the elements of gs through its channels.
+//
+// If splittable is false, stage is fused only into a sequential pipeline,
+// because it has state over all the elements in encounter order, such as
+// Limit. In that case, if gs is split-based, its parts are pulled in order
+// by the new sequential stream. If gs is neither pull-based nor split-based,
+// fuse returns nil and gs is left intact.
+func fuse[R, T any](
+	gs *genericStream[T],
+	splittable bool,
+	stage func(pull pullFunc[T]) pullFunc[R],
+) *genericStream[R] {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	if gs.closed {
+		panic("stream has already been closed")
+	}
+	if gs.pull == nil && gs.split == nil && !splittable {
+		return nil
+	}
+
+	newGS := &genericStream[R]{
+		parallel:      gs.parallel,
+		parallelCount: gs.parallelCount,
+		unordered:     gs.unordered,
+		reordered:     gs.reordered,
+
+		terminalCloseCount: gs.terminalCloseCount,
+
+		stages:  gs.stages,
+		failure: gs.failure,
+		cancel:  gs.cancel,
+
+		nextReq:  make(chan struct{}, cap(gs.nextReq)),
+		nextData: make(chan orderedData[R], cap(gs.nextData)),
+	}
+	switch {
+	case gs.pull != nil:
+		newGS.pull = stage(gs.pull)
+	case gs.split == nil:
+		fused, release := gs.fused, gs.release
+		if fused == nil {
+			// gs is channel-based, and is left open for its goroutines.
+			fused, release = gs.channelParts(), gs.releaseChannels()
+		}
+		newGS.fused = func(n int) []pullFunc[R] {
+			parts := make([]pullFunc[R], n)
+			for k, part := range fused(n) {
+				parts[k] = stage(part)
+			}
+			return parts
+		}
+		newGS.release = release
+		if gs.fused == nil {
+			return newGS
+		}
+	case !splittable:
+		newGS.parallel = false
+		newGS.parallelCount = 1
+		newGS.terminalCloseCount = 0
+		newGS.nextReq = make(chan struct{})
+		newGS.nextData = make(chan orderedData[R])
+		newGS.pull = stage(gs.split(1)[0])
+	}
+	if newGS.pull != nil {
+		newGS.prevDone = make(chan struct{})
+	}
+	if split := gs.split; split != nil && splittable {
+		newGS.split = func(n int) []pullFunc[R] {
+			parts := make([]pullFunc[R], n)
+			for k, part := range split(n) {
+				parts[k] = stage(part)
+			}
+			return parts
+		}
+	}
+
+	gs.pull, gs.split, gs.fused = nil, nil, nil
+	gs.closed = true
+	return newGS
+}
+
+// channelParts returns a function which returns n functions, each of which
+// pulls the next element of gs through its channels.
+func (gs *genericStream[T]) channelParts() func(n int) []pullFunc[T] {
+	return func(n int) []pullFunc[T] {
+		parts := make([]pullFunc[T], n)
+		for k := range parts {
+			parts[k] = func() (orderedData[T], bool) {
+				gs.nextReq <- struct{}{}
+				od, ok := <-gs.nextData
+				return od, ok
+			}
+		}
+		return parts
+	}
+}
+
+// releaseChannels returns a function which closes the request channel of
+// gs, so that the goroutines of gs stop. The function may be called more
+// than once.
+func (gs *genericStream[T]) releaseChannels() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(gs.nextReq)
+		})
+	}
+}
+
+// takePull returns the pull function of gs, and closes gs because its
+// elements are consumed through the returned function. If gs is not
+// pull-based, takePull returns nil and gs is left intact.
+func (gs *genericStream[T]) takePull() pullFunc[T] {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	if gs.closed {
+		panic("stream has already been closed")
+	}
+
+	pull := gs.pull
+	if pull != nil {
+		gs.pull, gs.split = nil, nil
+		gs.closed = true
+	}
+	return pull
+}
+
+// takeSplit returns the split function of gs, and closes gs as takePull
+// does. If gs is not split-based, takeSplit returns nil and gs is left
+// intact.
+func (gs *genericStream[T]) takeSplit() func(n int) []pullFunc[T] {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	if gs.closed {
+		panic("stream has already been closed")
+	}
+
+	split := gs.split
+	if split != nil {
+		gs.pull, gs.split = nil, nil
+		gs.closed = true
+	}
+	return split
+}
+
+// parallelSplit returns a parallel stream of n goroutines, each of which
+// pulls a part of the elements of gs, if gs is split-based. Otherwise,
+// parallelSplit returns nil.
+func (gs *genericStream[T]) parallelSplit(n int) *genericStream[T] {
+	split := gs.takeSplit()
+	if split == nil {
+		return nil
+	}
+
+	return &genericStream[T]{
+		parallel:      true,
+		parallelCount: n,
+		unordered:     gs.unordered,
+		dense:         gs.dense,
+
+		terminalCloseCount: n,
+
+		stages:  gs.stages,
+		failure: gs.failure,
+		cancel:  gs.cancel,
+
+		nextReq:  make(chan struct{}, n),
+		nextData: make(chan orderedData[T], n),
+		split:    split,
+	}
+}
+
+// takeOrderedPull returns a function which pulls the elements of gs in
+// encounter order, and closes gs as takePull does. If gs is split-based, its
+// parts are pulled in order. If gs is neither pull-based nor split-based,
+// takeOrderedPull returns nil and gs is left intact.
+func (gs *genericStream[T]) takeOrderedPull() pullFunc[T] {
+	if pull := gs.takePull(); pull != nil {
+		return pull
+	}
+	if split := gs.takeSplit(); split != nil {
+		return split(1)[0]
+	}
+	return nil
+}
+
+// runSplit runs op for each part of the elements of gs in its own
+// goroutine, and waits for all of them, if gs is split-based. runSplit
+// reports whether op has been run.
+func (gs *genericStream[T]) runSplit(op func(k int, part pullFunc[T])) bool {
+	split := gs.takeSplit()
+	if split == nil {
+		return false
+	}
+
+	var wg sync.WaitGroup
+	for k, part := range split(gs.parallelCount) {
+		wg.Add(1)
+		go func(k int, part pullFunc[T]) {
+			defer wg.Done()
+			op(k, part)
+		}(k, part)
+	}
+	wg.Wait()
+	return true
+}
+
+// servePull serves the elements pulled by the pull function through the
+// channels of gs, for operations which are not fused into pull.
+func (gs *genericStream[T]) servePull(pull pullFunc[T]) {
+	for gs.getNextReq() {
+		od, ok := pull()
+		if !ok {
+			break
+		}
+		gs.nextData <- od
+	}
+	close(gs.nextData)
+	close(gs.prevDone)
+	gs.discard(gs.nextReq)
+}
+
+// serveSplit serves the elements of the parts split by the split function
+// through the channels of gs by its parallel goroutines, for operations
+// which are not fused into split.
+func (gs *genericStream[T]) serveSplit(split func(n int) []pullFunc[T]) {
+	var wg sync.WaitGroup
+	for _, part := range split(gs.parallelCount) {
+		wg.Add(1)
+		go func(part pullFunc[T]) {
+			defer wg.Done()
+
+			// An element is pulled before a request is taken, because a
+			// request taken by a goroutine whose part is exhausted would
+			// never be answered while the other goroutines still serve.
+			for od, ok := part(); ok && gs.getNextReq(); od, ok = part() {
+				gs.nextData <- od
+			}
+		}(part)
+	}
+
+	go func() {
+		wg.Wait()
+		close(gs.nextData)
+		gs.discard(gs.nextReq)
+	}()
+}
+
+// serveFused serves the elements pulled through the stages fused onto a
+// channel-based stream through the channels of gs by its parallel
+// goroutines, and calls release once all of them are done.
+func (gs *genericStream[T]) serveFused(
+	fused func(n int) []pullFunc[T],
+	release func(),
+) {
+	// exhausted is closed when the channel-based stream is exhausted, which
+	// is shared by all the goroutines, so that the others stop without
+	// taking a request which would never be answered.
+	exhausted := make(chan struct{})
+	var once sync.Once
+
+	var wg sync.WaitGroup
+	for _, part := range fused(gs.parallelCount) {
+		wg.Add(1)
+		go func(part pullFunc[T]) {
+			defer wg.Done()
+
+			for {
+				select {
+				case _, ok := <-gs.nextReq:
+					if !ok {
+						return
+					}
+				case <-exhausted:
+					return
+				case <-gs.cancel.canceled():
+					return
+				}
+
+				od, ok := part()
+				if !ok {
+					once.Do(func() { close(exhausted) })
+					return
+				}
+				gs.nextData <- od
+			}
+		}(part)
+	}
+
+	go func() {
+		wg.Wait()
+		close(gs.nextData)
+		release()
+		gs.discard(gs.nextReq)
+	}()
+}
@@ -0,0 +1,37 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Ints returns an infinite sequential ordered Stream of pseudo-random int
+// values, each in [0, bound), produced by r.
+//
+// The Stream uses r exclusively while elements are consumed, so the same
+// seed of r always produces the same Stream.
+func Ints(r *rand.Rand, bound int) Stream[int] {
+	if bound <= 0 {
+		panic(fmt.Sprintf("bound must be positive: %v", bound))
+	}
+	return Generate(func() int {
+		return r.Intn(bound)
+	})
+}
+
+// Float64s returns an infinite sequential ordered Stream of pseudo-random
+// float64 values, each in [0.0, 1.0), produced by r.
+func Float64s(r *rand.Rand) Stream[float64] {
+	return Generate(r.Float64)
+}
+
+// Perm returns a sequential ordered Stream of a pseudo-random permutation
+// of the integers in [0, n), produced by r.
+func Perm(r *rand.Rand, n int) Stream[int] {
+	if n < 0 {
+		panic(fmt.Sprintf("n must not be negative: %v", n))
+	}
+	return Of(r.Perm(n)...)
+}
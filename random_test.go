@@ -0,0 +1,50 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestRandom_Ints(t *testing.T) {
+	result := Ints(rand.New(rand.NewSource(1)), 10).Limit(1000).ToSlice()
+	if len(result) != 1000 {
+		t.Fatalf("len(result) is %d, want 1000", len(result))
+	}
+	for i, v := range result {
+		if v < 0 || v >= 10 {
+			t.Errorf("result[%d] is %d, want [0, 10)", i, v)
+		}
+	}
+
+	again := Ints(rand.New(rand.NewSource(1)), 10).Limit(1000).ToSlice()
+	if !slices.Equal(result, again) {
+		t.Errorf("same seed produces different streams")
+	}
+}
+
+func TestRandom_Float64s(t *testing.T) {
+	result := Float64s(rand.New(rand.NewSource(1))).Limit(1000).ToSlice()
+	if len(result) != 1000 {
+		t.Fatalf("len(result) is %d, want 1000", len(result))
+	}
+	for i, v := range result {
+		if v < 0.0 || v >= 1.0 {
+			t.Errorf("result[%d] is %v, want [0.0, 1.0)", i, v)
+		}
+	}
+}
+
+func TestRandom_Perm(t *testing.T) {
+	for _, n := range [...]int{0, 1, 100} {
+		result := Perm(rand.New(rand.NewSource(1)), n).ToSlice()
+		slices.Sort(result)
+
+		want := Range(0, n).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("sorted result is %v, want %v", result, want)
+		}
+	}
+}
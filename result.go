@@ -0,0 +1,89 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"fmt"
+
+	"github.com/YoshikiShibata/gostream/function"
+)
+
+// Result is a container object which holds either a value or an error, as
+// the outcome of a fallible operation. If a value is held, IsOk() returns
+// true, otherwise IsErr() returns true.
+// The zero value for Result holds the zero value of T.
+type Result[T any] struct {
+	value T
+	err   error
+}
+
+// Get returns the value if it is held. Otherwise, Get panics.
+func (r Result[T]) Get() T {
+	if r.err == nil {
+		return r.value
+	}
+	panic(fmt.Sprintf("result is an error: %v", r.err))
+}
+
+// Err returns the error if it is held, otherwise nil.
+func (r Result[T]) Err() error {
+	return r.err
+}
+
+// Unwrap returns the value and the error, in the usual manner of Go.
+func (r Result[T]) Unwrap() (T, error) {
+	return r.value, r.err
+}
+
+// IsOk returns true if a value is held, otherwise false.
+func (r Result[T]) IsOk() bool {
+	return r.err == nil
+}
+
+// IsErr returns true if an error is held, otherwise false.
+func (r Result[T]) IsErr() bool {
+	return r.err != nil
+}
+
+// IfOk performs the given action with a value if the value is held,
+// otherwise does nothing.
+func (r Result[T]) IfOk(action function.Consumer[T]) {
+	if r.err == nil {
+		action(r.value)
+	}
+}
+
+// OrElse returns a value if the value is held, otherwise returns other.
+func (r Result[T]) OrElse(other T) T {
+	if r.err == nil {
+		return r.value
+	}
+	return other
+}
+
+// OrElseGet returns a value if the value is held, otherwise returns the
+// result produced by the given function with the error.
+func (r Result[T]) OrElseGet(f function.Function[error, T]) T {
+	if r.err == nil {
+		return r.value
+	}
+	return f(r.err)
+}
+
+// Optional returns an Optional describing a value if the value is held,
+// otherwise returns an empty Optional.
+func (r Result[T]) Optional() *Optional[T] {
+	if r.err == nil {
+		return OptionalOf(r.value)
+	}
+	return OptionalEmpty[T]()
+}
+
+// String returns a string representation of this Result suitable for
+// debugging.
+func (r Result[T]) String() string {
+	if r.err == nil {
+		return fmt.Sprintf("Ok[%v]", r.value)
+	}
+	return fmt.Sprintf("Err[%v]", r.err)
+}
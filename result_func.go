@@ -0,0 +1,145 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"errors"
+
+	"github.com/YoshikiShibata/gostream/function"
+)
+
+// Ok returns a Result holding the given value.
+func Ok[T any](value T) Result[T] {
+	return Result[T]{value: value}
+}
+
+// Err returns a Result holding the given error. If err is nil, Err panics.
+func Err[T any](err error) Result[T] {
+	if err == nil {
+		panic("err must not be nil")
+	}
+	return Result[T]{err: err}
+}
+
+// ResultOf returns a Result holding err if err is not nil, otherwise value.
+// This is convenient to wrap a function returning a value and an error.
+func ResultOf[T any](value T, err error) Result[T] {
+	if err != nil {
+		return Result[T]{err: err}
+	}
+	return Result[T]{value: value}
+}
+
+// ResultMap returns a Result holding the result of applying the given mapping
+// function to a value if the value is held, otherwise returns a Result holding
+// the same error.
+func ResultMap[U, T any](
+	r Result[T],
+	mapper function.Function[T, U],
+) Result[U] {
+	if r.err != nil {
+		return Result[U]{err: r.err}
+	}
+	return Result[U]{value: mapper(r.value)}
+}
+
+// ResultFlatMap returns the result of applying the given Result-bearing
+// mapping function to a value if the value is held, otherwise returns a Result
+// holding the same error.
+func ResultFlatMap[U, T any](
+	r Result[T],
+	mapper function.Function[T, Result[U]],
+) Result[U] {
+	if r.err != nil {
+		return Result[U]{err: r.err}
+	}
+	return mapper(r.value)
+}
+
+// Lift returns a Function which applies the fallible function f and wraps
+// its value and error in a Result, so that f can be passed to Map and the
+// like.
+func Lift[T, R any](f function.CheckedFunction[T, R]) function.Function[T, Result[R]] {
+	return func(t T) Result[R] {
+		return ResultOf(f(t))
+	}
+}
+
+// MapResult returns a stream consisting of the Results of applying the
+// given fallible function to the elements of stream. Unlike MapErr, an error
+// doesn't abort the stream, but is held by the Result for the element.
+func MapResult[T, R any](
+	stream Stream[T],
+	mapper function.CheckedFunction[T, R],
+) Stream[Result[R]] {
+	return Map(stream, Lift(mapper))
+}
+
+// FilterResult returns a stream consisting of the Results holding the
+// elements of stream that match the given fallible predicate. Unlike
+// FilterErr, an error doesn't abort the stream, but is held by the Result
+// for the element.
+func FilterResult[T any](
+	stream Stream[T],
+	predicate function.CheckedPredicate[T],
+) Stream[Result[T]] {
+	return MapOrSkip(stream, func(t T) *Optional[Result[T]] {
+		matched, err := predicate(t)
+		switch {
+		case err != nil:
+			return OptionalOf(Err[T](err))
+		case matched:
+			return OptionalOf(Ok(t))
+		}
+		return OptionalEmpty[Result[T]]()
+	})
+}
+
+// ForEachResult performs the fallible action for each element of stream.
+// Unlike TryForEach, all elements are consumed even if the action fails, and
+// the errors are returned joined by errors.Join in encounter order.
+func ForEachResult[T any](
+	stream Stream[T],
+	action function.CheckedConsumer[T],
+) error {
+	_, err := PartitionResults(MapResult(stream, func(t T) (struct{}, error) {
+		return struct{}{}, action(t)
+	}))
+	return err
+}
+
+// CollectOrError returns the values held by the Results of stream in
+// encounter order. If a Result holds an error, no more elements are
+// requested from stream and the error is returned. A parallel stream is made
+// sequential by Sequential, which collects the elements only if the stream is
+// ordered and some elements have been dropped, such as by Filter.
+func CollectOrError[T any](stream Stream[Result[T]]) ([]T, error) {
+	var values []T
+	err := stream.Sequential().TryForEach(func(r Result[T]) error {
+		if r.err != nil {
+			return r.err
+		}
+		values = append(values, r.value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// PartitionResults returns the values held by the Results of stream, and
+// the errors held by the others joined by errors.Join, both in encounter
+// order. If no Result holds an error, the returned error is nil.
+func PartitionResults[T any](stream Stream[Result[T]]) ([]T, error) {
+	var values []T
+	var errs []error
+	for _, r := range stream.ToSlice() {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		} else {
+			values = append(values, r.value)
+		}
+	}
+	return values, errors.Join(errs...)
+}
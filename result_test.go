@@ -0,0 +1,260 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestResult_Ok(t *testing.T) {
+	r := Ok(10)
+
+	if !r.IsOk() || r.IsErr() {
+		t.Errorf("IsOk() is %v, IsErr() is %v, want true, false",
+			r.IsOk(), r.IsErr())
+	}
+	if r.Get() != 10 {
+		t.Errorf("r.Get() is %d, want 10", r.Get())
+	}
+	if r.Err() != nil {
+		t.Errorf("r.Err() is %v, want nil", r.Err())
+	}
+	if r.OrElse(20) != 10 {
+		t.Errorf("r.OrElse(20) is %d, want 10", r.OrElse(20))
+	}
+	if o := r.Optional(); o.Get() != 10 {
+		t.Errorf("r.Optional() is %v, want Optional[10]", o)
+	}
+	if s := r.String(); s != "Ok[10]" {
+		t.Errorf("r.String() is %q, want %q", s, "Ok[10]")
+	}
+
+	called := false
+	r.IfOk(func(v int) { called = true })
+	if !called {
+		t.Errorf("IfOk doesn't perform the action")
+	}
+}
+
+func TestResult_Err(t *testing.T) {
+	errFailed := errors.New("failed")
+	r := Err[int](errFailed)
+
+	if r.IsOk() || !r.IsErr() {
+		t.Errorf("IsOk() is %v, IsErr() is %v, want false, true",
+			r.IsOk(), r.IsErr())
+	}
+	if r.Err() != errFailed {
+		t.Errorf("r.Err() is %v, want %v", r.Err(), errFailed)
+	}
+	if r.OrElse(20) != 20 {
+		t.Errorf("r.OrElse(20) is %d, want 20", r.OrElse(20))
+	}
+	if v := r.OrElseGet(func(err error) int { return len(err.Error()) }); v != 6 {
+		t.Errorf("r.OrElseGet() is %d, want 6", v)
+	}
+	if o := r.Optional(); o.IsPresent() {
+		t.Errorf("r.Optional() is %v, want empty", o)
+	}
+	if s := r.String(); s != "Err[failed]" {
+		t.Errorf("r.String() is %q, want %q", s, "Err[failed]")
+	}
+
+	r.IfOk(func(v int) {
+		t.Errorf("IfOk performs the action")
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("r.Get() doesn't panic")
+		}
+	}()
+	r.Get()
+}
+
+func TestResult_MapFunc(t *testing.T) {
+	r := ResultMap(Ok(10), strconv.Itoa)
+	if r.Get() != "10" {
+		t.Errorf("r.Get() is %q, want %q", r.Get(), "10")
+	}
+
+	errFailed := errors.New("failed")
+	r = ResultMap(Err[int](errFailed), strconv.Itoa)
+	if r.Err() != errFailed {
+		t.Errorf("r.Err() is %v, want %v", r.Err(), errFailed)
+	}
+}
+
+func TestResult_FlatMapFunc(t *testing.T) {
+	atoi := func(s string) Result[int] {
+		return ResultOf(strconv.Atoi(s))
+	}
+
+	if r := ResultFlatMap(Ok("10"), atoi); r.Get() != 10 {
+		t.Errorf("r.Get() is %d, want 10", r.Get())
+	}
+	if r := ResultFlatMap(Ok("x"), atoi); r.IsOk() {
+		t.Errorf("r is %v, want an error", r)
+	}
+
+	errFailed := errors.New("failed")
+	if r := ResultFlatMap(Err[string](errFailed), atoi); r.Err() != errFailed {
+		t.Errorf("r.Err() is %v, want %v", r.Err(), errFailed)
+	}
+}
+
+func TestResult_CollectOrErrorFunc(t *testing.T) {
+	atoi := func(s string) Result[int] {
+		return ResultOf(strconv.Atoi(s))
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of("1", "2", "3")
+		if parallel {
+			s = s.Parallel()
+		}
+
+		values, err := CollectOrError(Map(s, atoi))
+		if err != nil {
+			t.Errorf("[%v] err is %v, want nil", parallel, err)
+		}
+		if !slices.Equal(values, []int{1, 2, 3}) {
+			t.Errorf("[%v] values is %v, want [1 2 3]", parallel, values)
+		}
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of("1", "x", "3", "y")
+		if parallel {
+			s = s.Parallel()
+		}
+
+		values, err := CollectOrError(Map(s, atoi))
+		if err == nil || !strings.Contains(err.Error(), `"x"`) {
+			t.Errorf("[%v] err is %v, want the error for x", parallel, err)
+		}
+		if values != nil {
+			t.Errorf("[%v] values is %v, want nil", parallel, values)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		errTooLarge := errors.New("too large")
+		for _, parallel := range []bool{false, true} {
+			s := Iterate(0, func(v int) int {
+				return v + 1
+			})
+			if parallel {
+				s = s.Parallel()
+			}
+
+			_, err := CollectOrError(Map(s, func(v int) Result[int] {
+				if v >= 10 {
+					return Err[int](errTooLarge)
+				}
+				return Ok(v)
+			}))
+			if err != errTooLarge {
+				t.Errorf("[%v] err is %v, want %v", parallel, err, errTooLarge)
+			}
+		}
+	})
+}
+
+func TestResult_PartitionResultsFunc(t *testing.T) {
+	errX := errors.New("x")
+	errY := errors.New("y")
+
+	for _, parallel := range []bool{false, true} {
+		s := Of(Ok(1), Err[int](errX), Ok(3), Err[int](errY))
+		if parallel {
+			s = s.Parallel()
+		}
+
+		values, err := PartitionResults(s)
+		if !slices.Equal(values, []int{1, 3}) {
+			t.Errorf("[%v] values is %v, want [1 3]", parallel, values)
+		}
+		if !errors.Is(err, errX) || !errors.Is(err, errY) {
+			t.Errorf("[%v] err is %v, want x and y", parallel, err)
+		}
+		if err.Error() != "x\ny" {
+			t.Errorf("[%v] err.Error() is %q, want %q", parallel, err, "x\ny")
+		}
+	}
+
+	values, err := PartitionResults(Of(Ok(1), Ok(2)))
+	if err != nil {
+		t.Errorf("err is %v, want nil", err)
+	}
+	if !slices.Equal(values, []int{1, 2}) {
+		t.Errorf("values is %v, want [1 2]", values)
+	}
+}
+
+func TestResult_MapResultFunc(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of("1", "x", "3")
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		values, err := PartitionResults(MapResult(s, strconv.Atoi))
+		if !slices.Equal(values, []int{1, 3}) {
+			t.Errorf("[%v] values is %v, want [1 3]", parallel, values)
+		}
+		if err == nil || !strings.Contains(err.Error(), `"x"`) {
+			t.Errorf("[%v] err is %v, want a parse error of \"x\"", parallel, err)
+		}
+	}
+}
+
+func TestResult_FilterResultFunc(t *testing.T) {
+	errNegative := errors.New("negative")
+	isEven := func(v int) (bool, error) {
+		if v < 0 {
+			return false, errNegative
+		}
+		return v%2 == 0, nil
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(1, 2, -1, 4, 5)
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := FilterResult(s, isEven).ToSlice()
+		want := []Result[int]{Ok(2), Err[int](errNegative), Ok(4)}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestResult_ForEachResultFunc(t *testing.T) {
+	var visited []int
+	err := ForEachResult(Range(0, 5), func(v int) error {
+		visited = append(visited, v)
+		if v%2 == 1 {
+			return fmt.Errorf("%d is odd", v)
+		}
+		return nil
+	})
+
+	if !slices.Equal(visited, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("visited is %v, want all elements", visited)
+	}
+	if err == nil || err.Error() != "1 is odd\n3 is odd" {
+		t.Errorf("err is %v, want joined errors of 1 and 3", err)
+	}
+
+	if err := ForEachResult(Range(0, 5), func(int) error { return nil }); err != nil {
+		t.Errorf("err is %v, want nil", err)
+	}
+}
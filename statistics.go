@@ -1,65 +1,255 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 )
 
+// SummaryStatistics is summary statistics, such as count, sum, min, max and
+// average, of values of type T. If T is an integer type, the sum, min and max
+// are kept in T, and the average is computed from a float64 sum, so it is not
+// affected by an overflow of the sum in T. If T is a floating-point type, the
+// statistics are computed as by Float64SummaryStatistics.
 type SummaryStatistics[T Number] struct {
-	count int64
-	sum   int64
-	min   int64
-	max   int64
+	count  int64
+	sum    T
+	min    T
+	max    T
+	floats Float64SummaryStatistics
 }
 
+// NewSummaryStatistics returns an empty SummaryStatistics with no values
+// recorded.
 func NewSummaryStatistics[T Number]() *SummaryStatistics[T] {
 	return &SummaryStatistics[T]{
-		count: 0,
-		sum:   0,
-		min:   math.MaxInt64,
-		max:   math.MinInt64,
+		floats: *NewFloat64SummaryStatistics(),
 	}
 }
 
-func (i *SummaryStatistics[T]) accept(value T) {
+// Accept records a value into the summary statistics. SummaryStatistics is
+// not safe for concurrent use: to summarize values in multiple goroutines,
+// use a SummaryStatistics for each goroutine and Combine them.
+func (i *SummaryStatistics[T]) Accept(value T) {
+	if i.count == 0 {
+		i.min = value
+		i.max = value
+	} else {
+		i.min = min(i.min, value)
+		i.max = max(i.max, value)
+	}
 	i.count++
-	i.sum += int64(value)
-	i.min = min(i.min, int64(value))
-	i.max = max(i.max, int64(value))
+	i.sum += value
+	i.floats.Accept(float64(value))
 }
 
-func (i *SummaryStatistics[T]) combine(other *SummaryStatistics[T]) {
+// Combine combines the state of other into this summary statistics.
+func (i *SummaryStatistics[T]) Combine(other *SummaryStatistics[T]) {
+	switch {
+	case other.count == 0:
+		return
+	case i.count == 0:
+		*i = *other
+		return
+	}
 	i.count += other.count
 	i.sum += other.sum
 	i.min = min(i.min, other.min)
 	i.max = max(i.max, other.max)
+	i.floats.Combine(&other.floats)
 }
 
+// GetCount returns the number of values recorded.
 func (i *SummaryStatistics[T]) GetCount() int64 {
 	return i.count
 }
 
-func (i *SummaryStatistics[T]) GetSum() int64 {
+// GetSum returns the sum of values, or 0 if no values have been recorded.
+func (i *SummaryStatistics[T]) GetSum() T {
+	if isFloat[T]() {
+		return T(i.floats.GetSum())
+	}
 	return i.sum
 }
 
-func (i *SummaryStatistics[T]) GetMin() int64 {
+// GetMin returns the minimum value. If no values have been recorded, GetMin
+// returns 0 for an integer type, or +Inf for a floating-point type.
+func (i *SummaryStatistics[T]) GetMin() T {
+	if isFloat[T]() {
+		return T(i.floats.GetMin())
+	}
 	return i.min
 }
 
-func (i *SummaryStatistics[T]) GetMax() int64 {
+// GetMax returns the maximum value. If no values have been recorded, GetMax
+// returns 0 for an integer type, or -Inf for a floating-point type.
+func (i *SummaryStatistics[T]) GetMax() T {
+	if isFloat[T]() {
+		return T(i.floats.GetMax())
+	}
 	return i.max
 }
 
+// GetAverage returns the arithmetic mean of values, or 0 if no values have
+// been recorded.
 func (i *SummaryStatistics[T]) GetAverage() float64 {
-	if i.count > 0 {
-		return float64(i.sum) / float64(i.count)
+	return i.floats.GetAverage()
+}
+
+// String returns a human-readable representation of the summary statistics,
+// such as "SummaryStatistics{count=3, sum=6, min=1, average=2.000000, max=3}".
+func (i *SummaryStatistics[T]) String() string {
+	return fmt.Sprintf("SummaryStatistics{count=%d, sum=%v, min=%v, average=%f, max=%v}",
+		i.GetCount(), i.GetSum(), i.GetMin(), i.GetAverage(), i.GetMax())
+}
+
+// MarshalJSON implements the json.Marshaler interface. The summary
+// statistics is encoded as an object with "count", "sum", "min", "max" and
+// "average" fields, where NaN and infinities are encoded as null.
+func (i *SummaryStatistics[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(summaryStatisticsJSON{
+		Count:   i.GetCount(),
+		Sum:     jsonNumber(i.GetSum()),
+		Min:     jsonNumber(i.GetMin()),
+		Max:     jsonNumber(i.GetMax()),
+		Average: jsonNumber(i.GetAverage()),
+	})
+}
+
+// Float64SummaryStatistics is summary statistics, such as count, sum, min,
+// max and average, of float64 values, like DoubleSummaryStatistics of Java.
+// The sum is computed by Kahan summation to reduce the numerical error.
+//
+// If any value is NaN, the sum, min, max and average are NaN. If values
+// include +Inf and -Inf, the sum and average are NaN; otherwise, if values
+// include +Inf or -Inf, the sum and average are the infinity. The min and
+// max distinguish -0.0 from +0.0 as math.Min and math.Max do.
+type Float64SummaryStatistics struct {
+	count int64
+	sum   compensatedSum
+	min   float64
+	max   float64
+}
+
+// NewFloat64SummaryStatistics returns an empty Float64SummaryStatistics with
+// no values recorded.
+func NewFloat64SummaryStatistics() *Float64SummaryStatistics {
+	return &Float64SummaryStatistics{
+		count: 0,
+		min:   math.Inf(1),
+		max:   math.Inf(-1),
+	}
+}
+
+// Accept records a value into the summary statistics. Float64SummaryStatistics
+// is not safe for concurrent use: to summarize values in multiple goroutines,
+// use a Float64SummaryStatistics for each goroutine and Combine them.
+func (f *Float64SummaryStatistics) Accept(value float64) {
+	f.count++
+	f.sum.add(value)
+	f.min = math.Min(f.min, value)
+	f.max = math.Max(f.max, value)
+}
+
+// Combine combines the state of other into this summary statistics.
+func (f *Float64SummaryStatistics) Combine(other *Float64SummaryStatistics) {
+	f.count += other.count
+	f.sum.combine(&other.sum)
+	f.min = math.Min(f.min, other.min)
+	f.max = math.Max(f.max, other.max)
+}
+
+// GetCount returns the number of values recorded.
+func (f *Float64SummaryStatistics) GetCount() int64 {
+	return f.count
+}
+
+// GetSum returns the sum of values, or 0 if no values have been recorded.
+func (f *Float64SummaryStatistics) GetSum() float64 {
+	return f.sum.value()
+}
+
+// GetMin returns the minimum value, or +Inf if no values have been recorded.
+func (f *Float64SummaryStatistics) GetMin() float64 {
+	return f.min
+}
+
+// GetMax returns the maximum value, or -Inf if no values have been recorded.
+func (f *Float64SummaryStatistics) GetMax() float64 {
+	return f.max
+}
+
+// GetAverage returns the arithmetic mean of values, or 0 if no values have
+// been recorded.
+func (f *Float64SummaryStatistics) GetAverage() float64 {
+	if f.count > 0 {
+		return f.GetSum() / float64(f.count)
 	}
 	return 0.0
 }
 
-func (i *SummaryStatistics[T]) String() string {
-	return fmt.Sprintf("%#v", i)
+// String returns a human-readable representation of the summary statistics,
+// such as "Float64SummaryStatistics{count=2, sum=3.000000, min=1.000000,
+// average=1.500000, max=2.000000}".
+func (f *Float64SummaryStatistics) String() string {
+	return fmt.Sprintf("Float64SummaryStatistics{count=%d, sum=%f, min=%f, average=%f, max=%f}",
+		f.GetCount(), f.GetSum(), f.GetMin(), f.GetAverage(), f.GetMax())
+}
+
+// MarshalJSON implements the json.Marshaler interface as SummaryStatistics
+// does.
+func (f *Float64SummaryStatistics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(summaryStatisticsJSON{
+		Count:   f.GetCount(),
+		Sum:     jsonNumber(f.GetSum()),
+		Min:     jsonNumber(f.GetMin()),
+		Max:     jsonNumber(f.GetMax()),
+		Average: jsonNumber(f.GetAverage()),
+	})
+}
+
+// summaryStatisticsJSON is the JSON representation of summary statistics.
+type summaryStatisticsJSON struct {
+	Count   int64 `json:"count"`
+	Sum     any   `json:"sum"`
+	Min     any   `json:"min"`
+	Max     any   `json:"max"`
+	Average any   `json:"average"`
+}
+
+// jsonNumber returns v, or nil if v is NaN or an infinity, which cannot be
+// encoded in JSON.
+func jsonNumber[T Number](v T) any {
+	if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return v
+}
+
+// Moments is the mean, population variance, skewness and excess kurtosis of
+// float64 values, produced by MomentsCollector. If no values are present, all
+// fields are 0. If the variance is 0, the skewness and kurtosis are NaN.
+type Moments struct {
+	Count    int64
+	Mean     float64
+	Variance float64
+	Skewness float64
+	Kurtosis float64 // excess kurtosis, which is 0 for a normal distribution
+}
+
+func (m *MomentsAccumulator) toMoments() Moments {
+	if m.count == 0 {
+		return Moments{}
+	}
+
+	n := float64(m.count)
+	return Moments{
+		Count:    m.count,
+		Mean:     m.mean,
+		Variance: m.m2 / n,
+		Skewness: math.Sqrt(n) * m.m3 / math.Pow(m.m2, 1.5),
+		Kurtosis: n*m.m4/(m.m2*m.m2) - 3,
+	}
 }
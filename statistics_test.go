@@ -0,0 +1,104 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestSummaryStatistics_AcceptCombine(t *testing.T) {
+	shards := make([]*SummaryStatistics[int], 4)
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			stats := NewSummaryStatistics[int]()
+			for v := i * 25; v < (i+1)*25; v++ {
+				stats.Accept(v)
+			}
+			shards[i] = stats
+		}(i)
+	}
+	wg.Wait()
+
+	stats := NewSummaryStatistics[int]()
+	for _, shard := range shards {
+		stats.Combine(shard)
+	}
+
+	if stats.GetCount() != 100 || stats.GetSum() != 4950 ||
+		stats.GetMin() != 0 || stats.GetMax() != 99 ||
+		stats.GetAverage() != 49.5 {
+		t.Errorf("stats is %v, want count 100, sum 4950, min 0, max 99, average 49.5",
+			stats)
+	}
+}
+
+func TestFloat64SummaryStatistics_AcceptCombine(t *testing.T) {
+	stats1 := NewFloat64SummaryStatistics()
+	stats1.Accept(1.5)
+	stats1.Accept(-2.0)
+
+	stats2 := NewFloat64SummaryStatistics()
+	stats2.Accept(4.5)
+
+	stats1.Combine(stats2)
+	stats1.Combine(NewFloat64SummaryStatistics())
+
+	if stats1.GetCount() != 3 || stats1.GetSum() != 4.0 ||
+		stats1.GetMin() != -2.0 || stats1.GetMax() != 4.5 {
+		t.Errorf("stats1 is %v, want count 3, sum 4, min -2, max 4.5", stats1)
+	}
+}
+
+func TestSummaryStatistics_String(t *testing.T) {
+	stats := Summarize(Of(1, 2, 3))
+	want := "SummaryStatistics{count=3, sum=6, min=1, average=2.000000, max=3}"
+	if stats.String() != want {
+		t.Errorf("stats.String() is %q, want %q", stats.String(), want)
+	}
+
+	fstats := NewFloat64SummaryStatistics()
+	fstats.Accept(1)
+	fstats.Accept(2)
+	want = "Float64SummaryStatistics{count=2, sum=3.000000, min=1.000000, average=1.500000, max=2.000000}"
+	if fstats.String() != want {
+		t.Errorf("fstats.String() is %q, want %q", fstats.String(), want)
+	}
+}
+
+func TestSummaryStatistics_MarshalJSON(t *testing.T) {
+	for _, tc := range [...]struct {
+		stats json.Marshaler
+		want  string
+	}{
+		{
+			stats: Summarize(Of(1, 2, 3, 4)),
+			want:  `{"count":4,"sum":10,"min":1,"max":4,"average":2.5}`,
+		},
+		{
+			stats: Summarize(Empty[float64]()),
+			want:  `{"count":0,"sum":0,"min":null,"max":null,"average":0}`,
+		},
+		{
+			stats: Summarize(Of(0.5, math.Inf(1))),
+			want:  `{"count":2,"sum":null,"min":0.5,"max":null,"average":null}`,
+		},
+		{
+			stats: NewFloat64SummaryStatistics(),
+			want:  `{"count":0,"sum":0,"min":null,"max":null,"average":0}`,
+		},
+	} {
+		b, err := json.Marshal(tc.stats)
+		if err != nil || string(b) != tc.want {
+			t.Errorf("json.Marshal(%v) is %s, %v, want %s",
+				tc.stats, b, err, tc.want)
+		}
+	}
+}
@@ -1,52 +1,183 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
-import "github.com/YoshikiShibata/gostream/function"
+import (
+	"math/rand"
+	"time"
 
+	"github.com/YoshikiShibata/gostream/function"
+)
+
+// BaseStream is the base interface of streams.
 type BaseStream[T any] interface {
 	// Close closes this stream, causing all close handlers for this
 	// stream pipeline to be called.
 	Close()
 }
 
+// Stream is a sequence of elements supporting sequential and parallel
+// aggregate operations, like Stream of Java. A stream pipeline consists of a
+// source, zero or more intermediate operations, which return a new stream,
+// and a terminal operation, which produces a result or a side-effect.
 type Stream[T any] interface {
 	BaseStream[T]
 
+	// String returns a string representation of this stream suitable for
+	// debugging, which includes the named stages of the pipeline.
+	String() string
+
+	// Err returns the error which aborted this stream pipeline, or nil if it
+	// has not been aborted. It is typically called after a terminal
+	// operation.
+	Err() error
+
 	// Parallel returns an equivalent stream that is parallel. May return
 	// itself, either because the stream was alreay parallel, or because
 	// the underlying stream state was modified to be parallel.
 	Parallel() Stream[T]
 
+	// ParallelN returns an equivalent stream that is processed in parallel by
+	// n goroutines, regardless of GOMAXPROCS. May return itself if the stream
+	// is already processed in parallel by n goroutines.
+	ParallelN(n int) Stream[T]
+
+	// Unordered returns an equivalent stream whose encounter order of
+	// elements need not be kept, which allows operations such as ToSlice
+	// to skip the work of keeping the order. May return itself if the
+	// stream was already unordered.
+	Unordered() Stream[T]
+
+	// Sequential returns an equivalent stream that is sequential. May return
+	// itself if the stream was already sequential. If this stream is parallel
+	// and not unordered, its elements are emitted in encounter order: those
+	// which arrive earlier than their predecessors are held until their
+	// turn. If some elements of a sized source, such as Of or Range, have
+	// been dropped, such as by Filter, all elements are buffered to find the
+	// first one.
+	Sequential() Stream[T]
+
 	// Filter returns a stream consisting of the elements of this stream
 	// that match given predicate.
 	Filter(predicate function.Predicate[T]) Stream[T]
 
+	// Sample returns a stream consisting of the elements of this stream,
+	// each of which is kept independently with probability p, using the
+	// pseudo-random numbers produced by r.
+	Sample(p float64, r *rand.Rand) Stream[T]
+
 	// Sorted returns a stream consisting of the elements of this stream,
 	// according to the provided Less.
 	Sorted(cmp func(a, b T) int) Stream[T]
 
+	// Reverse returns a stream consisting of the elements of this stream in
+	// reverse encounter order. This stream must be finite.
+	Reverse() Stream[T]
+
+	// Shuffle returns a stream consisting of a pseudo-random permutation of
+	// the elements of this stream, produced by r. This stream must be finite.
+	// The permutation only depends on r and the encounter order of this
+	// stream. If this stream is parallel, the returned stream is parallel
+	// and unordered, so its elements may be processed in any order.
+	Shuffle(r *rand.Rand) Stream[T]
+
+	// Prepend returns a lazily concatenated stream whose elements are the
+	// given values followed by all the elements of this stream.
+	Prepend(values ...T) Stream[T]
+
+	// Append returns a lazily concatenated stream whose elements are all the
+	// elements of this stream followed by the given values.
+	Append(values ...T) Stream[T]
+
 	// Peek returns a stream consisting of the elements of this stream,
 	// additionally performing the provided action on each element as elements
 	// are consumed from the resulting steam.
 	Peek(action function.Consumer[T]) Stream[T]
 
+	// PeekNamed is the same as Peek, but the stage is named for debugging.
+	// The name and the number of elements passed through the stage are
+	// included in the string representation of the resulting stream and the
+	// streams following it.
+	PeekNamed(name string, action function.Consumer[T]) Stream[T]
+
 	// Limit returns a stream consisting of the elements of this stream,
 	// truncated to be no logner than maxSize in length.
+	// If this stream is parallel and not unordered, the first maxSize
+	// elements in encounter order are returned. If some elements of a sized
+	// source, such as Of or Range, have been dropped in parallel, for example
+	// by Filter, all elements are requested to find them; use Unordered to
+	// avoid it.
 	Limit(maxSize int) Stream[T]
 
 	// Skip returns a stream consisting of the remaining elements of this
 	// stream after discarding the first n elements of the stream.
 	// If this stream contians fewer than n elements then an empty stream
 	// will be returned.
+	// If this stream is parallel and not unordered, the first n elements in
+	// encounter order are discarded. If some elements of a sized source, such
+	// as Of or Range, have been dropped in parallel, for example by Filter,
+	// all elements are requested to find them; use Unordered to avoid it.
 	Skip(n int) Stream[T]
 
+	// StepBy returns a stream consisting of every n-th element of this
+	// stream, starting with the first element.
+	StepBy(n int) Stream[T]
+
+	// TakeWhile returns a stream consisting of the longest prefix of elements
+	// taken from this stream that match the given predicate.
+	TakeWhile(predicate function.Predicate[T]) Stream[T]
+
+	// DropWhile returns a stream consisting of the remaining elements of this
+	// stream after dropping the longest prefix of elements that match the
+	// given predicate.
+	DropWhile(predicate function.Predicate[T]) Stream[T]
+
+	// Buffer returns a stream consisting of the elements of this stream,
+	// which are requested ahead from this stream and buffered up to n
+	// elements, so that a slow consumer of the resulting stream doesn't stall
+	// the producer of this stream.
+	Buffer(n int) Stream[T]
+
+	// Debounce returns a stream consisting of the elements of this stream,
+	// each of which is emitted only after d has elapsed without another
+	// element being produced by this stream. The last element is emitted as
+	// soon as this stream ends.
+	Debounce(d time.Duration) Stream[T]
+
+	// WithTimeout returns a stream consisting of the elements of this stream,
+	// which is aborted if d has elapsed since the first element was
+	// requested. Err of the aborted stream returns ErrTimeout.
+	WithTimeout(d time.Duration) Stream[T]
+
 	// ForEach performs an action for each element of this stream.
 	ForEach(action function.Consumer[T])
 
+	// ForEachOrdered performs an action for each element of this stream, one
+	// at a time in encounter order, even if this stream is parallel. If some
+	// elements have been dropped in parallel, for example by Filter, all
+	// elements are buffered to restore the order, so the stream must be
+	// finite; use Unordered to avoid it.
+	ForEachOrdered(action function.Consumer[T])
+
+	// TryForEach performs an action for each element of this stream until the
+	// action returns an error. The first error is returned and the rest of
+	// this stream is not consumed. If the action never fails, the error which
+	// aborted this stream pipeline, if any, is returned.
+	TryForEach(action function.CheckedConsumer[T]) error
+
+	// Drain consumes and discards all elements of this stream, so that the
+	// side effects of the pipeline, such as Peek, are run to completion and
+	// the resources of its source, such as a file of FileLines, are released.
+	Drain()
+
 	// ToSlice returns a slice containing the elements of this stream.
 	ToSlice() []T
 
+	// ToSortedSlice returns a slice containing the elements of this stream,
+	// sorted according to the provided comparison function. The sort is
+	// stable: elements which compare equal keep their encounter order.
+	ToSortedSlice(cmp func(a, b T) int) []T
+
 	// Reduce performs a reduction on the elements of this stream, using
 	// the provided identity value and an accumulation function, and returns
 	// the reduced value.
@@ -68,6 +199,11 @@ type Stream[T any] interface {
 	// Count returns the count of elements in this stream.
 	Count() int
 
+	// Tally returns the counts of elements in this stream which match each of
+	// the given predicates, in a single traversal of this stream. The i-th
+	// count is for the i-th predicate.
+	Tally(predicates ...function.Predicate[T]) []int
+
 	// AnyMatch returns whether any elements of this stream match the provided
 	// predicate. May not evaluate the predicate on all elements if not
 	// necesary for determining the resulst. If the stream is empty then false
@@ -90,6 +226,17 @@ type Stream[T any] interface {
 	// stream or an empty Optional if the stream is empty.
 	FindFirst() *Optional[T]
 
+	// Single returns an Optional describing the sole element of this stream.
+	// If this stream is empty, an empty Optional and ErrNoElements are
+	// returned. If this stream has more than one element, an empty Optional
+	// and ErrMultipleElements are returned without consuming the rest.
+	Single() (*Optional[T], error)
+
+	// ElementAt returns an Optional describing the element at the position n
+	// in encounter order, or an empty Optional if this stream has n or fewer
+	// elements. The elements after the position are not consumed.
+	ElementAt(n int) *Optional[T]
+
 	// FindAny returns an Optional describing some element of the stream, or
 	// an empty Optional if the steam is empty.
 	//
@@ -1,11 +1,14 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
 	"cmp"
+	"fmt"
+	"math"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/YoshikiShibata/gostream/function"
 )
@@ -13,57 +16,315 @@ import (
 // Map returns a stream consisting of the results of applying the given
 // function to the elements of the given stream.
 func Map[T, R any](stream Stream[T], mapper function.Function[T, R]) Stream[R] {
+	gs := stream.(*genericStream[T])
+	newGS := fuse(gs, true, func(pull pullFunc[T]) pullFunc[R] {
+		return func() (orderedData[R], bool) {
+			od, ok := pull()
+			if !ok {
+				return orderedData[R]{}, false
+			}
+			return orderedData[R]{order: od.order, data: mapper(od.data)}, true
+		}
+	})
+	newGS.dense = gs.dense
+	return newGS
+}
+
+// MapOrSkip returns a stream consisting of the values of the results of
+// applying the given function to the elements of the given stream. Elements
+// for which the function returns an empty Optional are skipped.
+func MapOrSkip[T, R any](
+	stream Stream[T],
+	mapper function.Function[T, *Optional[R]],
+) Stream[R] {
 	gs := stream.(*genericStream[T])
 	gs.validateState()
 
-	nextReq := make(chan struct{})
-	nextData := make(chan orderedData[R])
+	newGS, getPrevData := newDerivedStream[R](gs)
+	newGS.parallel = gs.parallel
+	newGS.parallelCount = gs.parallelCount
+	newGS.terminalCloseCount = gs.terminalCloseCount
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			for newGS.getNextReq() {
+				for {
+					od, ok := getPrevData()
+					if !ok {
+						newGS.close()
+						return
+					}
+
+					r := mapper(od.data)
+					if r == nil {
+						panic("mapper returns nil")
+					}
+					if r.IsPresent() {
+						newGS.nextData <- orderedData[R]{
+							order: od.order,
+							data:  r.Get(),
+						}
+						break
+					}
+				}
+			}
+			newGS.close()
+		}()
+	}
 
-	closeCounter := gs.parallelCount
-	var lock sync.Mutex
+	return newGS
+}
 
-	closeChans := func() {
-		lock.Lock()
-		defer lock.Unlock()
+// FilterPresent returns a stream consisting of the values of the Optionals of
+// the given stream whose values are present, skipping empty ones.
+func FilterPresent[T any](stream Stream[*Optional[T]]) Stream[T] {
+	return MapOrSkip(stream, Identity[*Optional[T]])
+}
 
-		if closeCounter > 1 {
-			closeCounter--
-			return
+// OfType returns a stream consisting of the elements of the given stream
+// which can be asserted to the type R, typically from a stream of interface
+// values. The other elements are skipped.
+func OfType[R, T any](stream Stream[T]) Stream[R] {
+	return MapOrSkip(stream, func(t T) *Optional[R] {
+		if r, ok := any(t).(R); ok {
+			return OptionalOf(r)
 		}
+		return OptionalEmpty[R]()
+	})
+}
 
-		close(nextData)
-		close(gs.nextReq)
+// MapErr returns a stream consisting of the results of applying the given
+// fallible function to the elements of the given stream. If the function
+// returns an error, the stream is aborted and its Err returns the error.
+func MapErr[T, R any](
+	stream Stream[T],
+	mapper function.CheckedFunction[T, R],
+) Stream[R] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[R](gs)
+	newGS.parallel = gs.parallel
+	newGS.parallelCount = gs.parallelCount
+	newGS.terminalCloseCount = gs.terminalCloseCount
+	a := newAborter(newGS.ensureFailure())
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			for newGS.getNextReq() && !a.isAborted() {
+				od, ok := getPrevData()
+				if !ok {
+					break
+				}
+
+				r, err := mapper(od.data)
+				if err != nil {
+					a.abort(err)
+					break
+				}
+				newGS.nextData <- orderedData[R]{
+					order: od.order,
+					data:  r,
+				}
+			}
+			newGS.close()
+		}()
+	}
+
+	return newGS
+}
+
+// FilterErr returns a stream consisting of the elements of the given stream
+// that match the given fallible predicate. If the predicate returns an error,
+// the stream is aborted and its Err returns the error.
+func FilterErr[T any](
+	stream Stream[T],
+	predicate function.CheckedPredicate[T],
+) Stream[T] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[T](gs)
+	newGS.parallel = gs.parallel
+	newGS.parallelCount = gs.parallelCount
+	newGS.terminalCloseCount = gs.terminalCloseCount
+	a := newAborter(newGS.ensureFailure())
+
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
 		go func() {
-			for range nextReq {
+		loop:
+			for newGS.getNextReq() {
+				for !a.isAborted() {
+					od, ok := getPrevData()
+					if !ok {
+						break loop
+					}
+
+					matched, err := predicate(od.data)
+					if err != nil {
+						a.abort(err)
+						break loop
+					}
+					if matched {
+						newGS.nextData <- od
+						continue loop
+					}
+				}
+				break
 			}
+			newGS.close()
 		}()
 	}
 
+	return newGS
+}
+
+// MapWithTimeout returns a stream consisting of the results of applying the
+// given function to the elements of the given stream. If an application of
+// the function takes longer than d, the stream is aborted and its Err returns
+// ErrTimeout. The function is not interrupted but its result is discarded.
+func MapWithTimeout[T, R any](
+	stream Stream[T],
+	d time.Duration,
+	mapper function.Function[T, R],
+) Stream[R] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[R](gs)
+	newGS.parallel = gs.parallel
+	newGS.parallelCount = gs.parallelCount
+	newGS.terminalCloseCount = gs.terminalCloseCount
+	a := newAborter(newGS.ensureFailure())
+
 	parallelCount := gs.parallelCount
 	for i := 0; i < parallelCount; i++ {
 		go func() {
-			for range nextReq {
-				gs.nextReq <- struct{}{}
-				od, ok := <-gs.nextData
+		loop:
+			for newGS.getNextReq() {
+				if a.isAborted() {
+					break
+				}
+
+				od, ok := getPrevData()
 				if !ok {
-					closeChans()
-					return
+					break
 				}
-				r := mapper(od.data)
-				nextData <- orderedData[R]{
-					order: od.order,
-					data:  r,
+
+				results := make(chan R, 1)
+				go func() {
+					results <- mapper(od.data)
+				}()
+
+				timer := time.NewTimer(d)
+				select {
+				case r := <-results:
+					timer.Stop()
+					newGS.nextData <- orderedData[R]{
+						order: od.order,
+						data:  r,
+					}
+				case <-timer.C:
+					a.abort(ErrTimeout)
+					break loop
+				case <-a.aborted:
+					timer.Stop()
+					break loop
 				}
 			}
+			newGS.close()
 		}()
 	}
 
-	return &genericStream[R]{
-		parallel:      gs.parallel,
-		parallelCount: parallelCount,
-		nextReq:       nextReq,
-		nextData:      nextData,
+	return newGS
+}
+
+// MapConcurrent returns a stream consisting of the results of applying the
+// given function to the elements of the given stream. The function is applied
+// by up to n goroutines concurrently, regardless of whether stream is
+// parallel, and the results are produced in the order of the elements.
+// This is suitable for I/O bound functions such as network requests.
+func MapConcurrent[T, R any](
+	stream Stream[T],
+	n int,
+	mapper function.Function[T, R],
+) Stream[R] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	if n <= 0 {
+		panic(fmt.Sprintf("n must be positive: %v", n))
+	}
+
+	newGS, getPrevData := newDerivedStream[R](gs)
+	newGS.dense = gs.dense
+	// Elements are requested ahead, so the end of the source doesn't mean
+	// the end of this stream.
+	newGS.prevDone = nil
+
+	type job struct {
+		od     orderedData[T]
+		result chan orderedData[R]
+	}
+
+	jobs := make(chan job)
+	// results holds the channels of the results being computed in order.
+	// Together with the one being waited for, up to n results are computed.
+	results := make(chan chan orderedData[R], n-1)
+	done := make(chan struct{})
+	dispatched := make(chan struct{})
+
+	for i := 0; i < n; i++ {
+		go func() {
+			for j := range jobs {
+				j.result <- orderedData[R]{
+					order: j.od.order,
+					data:  mapper(j.od.data),
+				}
+			}
+		}()
 	}
+
+	// dispatcher
+	go func() {
+	loop:
+		for {
+			od, ok := getPrevData()
+			if !ok {
+				break
+			}
+
+			result := make(chan orderedData[R], 1)
+			select {
+			case results <- result:
+			case <-done:
+				break loop
+			}
+			jobs <- job{od: od, result: result}
+		}
+		close(jobs)
+		close(results)
+		close(dispatched)
+	}()
+
+	go func() {
+		for newGS.getNextReq() {
+			result, ok := <-results
+			if !ok {
+				break
+			}
+			newGS.nextData <- <-result
+		}
+
+		close(done)
+		<-dispatched
+		newGS.close()
+	}()
+
+	return newGS
 }
 
 // FlatMap returns a stream consisting of the results of replacing each
@@ -79,13 +340,24 @@ func FlatMap[T, R any](
 	nextReq := make(chan struct{})
 	nextData := make(chan orderedData[R])
 
+	// Always return non-parallel stream
+	newGS := &genericStream[R]{
+		parallelCount: 1,
+		unordered:     gs.unordered,
+		stages:        gs.stages,
+		failure:       gs.failure,
+		cancel:        gs.cancel,
+		nextReq:       nextReq,
+		nextData:      nextData,
+	}
+
 	var rgs *genericStream[R]
 
 	offset := uint64(0)
 	lastOrder := uint64(0)
 
 	go func() {
-		for range nextReq {
+		for newGS.getNextReq() {
 			for {
 				if rgs == nil {
 					gs.nextReq <- struct{}{}
@@ -102,6 +374,7 @@ func FlatMap[T, R any](
 
 					r := mapper(od.data)
 					rgs = r.(*genericStream[R])
+					rgs.validateState()
 				}
 
 				rgs.nextReq <- struct{}{}
@@ -120,97 +393,480 @@ func FlatMap[T, R any](
 				}
 			}
 		}
+
+		// no more elements are requested, so the streams are released.
+		if rgs != nil {
+			close(rgs.nextReq)
+		}
+		close(nextData)
+		close(gs.nextReq)
+		newGS.discard(nextReq)
 	}()
 
-	// Always return non-parallel stream
-	return &genericStream[R]{
-		parallelCount: 1,
-		nextReq:       nextReq,
-		nextData:      nextData,
-	}
+	return newGS
+}
+
+// FlatMapSlice returns a stream consisting of the elements of the slices
+// produced by applying the provided mapping function to each element of
+// stream. Unlike FlatMap, no stream is created for each element.
+func FlatMapSlice[T, R any](
+	stream Stream[T],
+	mapper function.Function[T, []R],
+) Stream[R] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[R](gs.inEncounterOrder())
+
+	go func() {
+		var rs []R
+
+		// The elements of gs are got in encounter order, so the order of
+		// each element of the slices is the order of the element of gs
+		// shifted by the number of elements expanded before it.
+		order := uint64(0)
+
+		for newGS.getNextReq() {
+			for len(rs) == 0 {
+				od, ok := getPrevData()
+				if !ok {
+					newGS.close()
+					return
+				}
+				rs = mapper(od.data)
+			}
+
+			newGS.nextData <- orderedData[R]{
+				order: order,
+				data:  rs[0],
+			}
+			order++
+			rs = rs[1:]
+		}
+		newGS.close()
+	}()
+
+	return newGS
+}
+
+// FlattenOptional is an alias of FilterPresent, named after Flatten.
+func FlattenOptional[T any](stream Stream[*Optional[T]]) Stream[T] {
+	return FilterPresent(stream)
+}
+
+// Flatten returns a stream consisting of all the elements of each stream of
+// stream, in encounter order.
+func Flatten[T any](stream Stream[Stream[T]]) Stream[T] {
+	gs := stream.(*genericStream[Stream[T]])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[T](gs.inEncounterOrder())
+
+	go func() {
+		var inner *genericStream[T]
+		order := uint64(0)
+
+		for newGS.getNextReq() {
+			for {
+				if inner == nil {
+					od, ok := getPrevData()
+					if !ok {
+						newGS.close()
+						return
+					}
+					inner = od.data.(*genericStream[T])
+					inner.validateState()
+
+					// the elements of each stream are flattened in
+					// encounter order.
+					inner = inner.inEncounterOrder()
+				}
+
+				inner.nextReq <- struct{}{}
+				od, ok := <-inner.nextData
+				if ok {
+					newGS.nextData <- orderedData[T]{
+						order: order,
+						data:  od.data,
+					}
+					order++
+					break
+				}
+				close(inner.nextReq)
+				inner = nil
+			}
+		}
+
+		if inner != nil {
+			close(inner.nextReq)
+		}
+		newGS.close()
+	}()
+
+	return newGS
 }
 
 // Returns a sequential ordered stream whose elements are the specified
-// values.
+// values. The elements are pulled lazily without a goroutine until an
+// operation which needs channels is applied, and split into contiguous parts
+// if the stream is made parallel.
 func Of[T any](data ...T) Stream[T] {
-	nextReq := make(chan struct{})
-	nextData := make(chan orderedData[T])
-	prevDone := make(chan struct{})
+	return newSizedStream(uint64(len(data)), func(i uint64) T {
+		return data[i]
+	})
+}
+
+// Distinct returns a stream consisting of the distinct elements
+// (according to ==) of this stream.
+func Distinct[T comparable](stream Stream[T]) Stream[T] {
+	s := stream.(*genericStream[T])
+	s.validateState()
+
+	gs := &genericStream[T]{
+		parallelCount: 1,
+		unordered:     s.unordered,
+		stages:        s.stages,
+		failure:       s.failure,
+		cancel:        s.cancel,
+		prevReq:       s.nextReq,
+		prevData:      s.nextData,
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[T]),
+	}
+
+	go func() {
+		seen := make(map[T]bool)
+
+		for gs.getNextReq() {
+			od, ok := gs.getPrevData()
+			if !ok {
+				gs.close()
+				return
+			}
+
+			for seen[od.data] {
+				od, ok = gs.getPrevData()
+				if !ok {
+					gs.close()
+					return
+				}
+			}
+			gs.nextData <- od
+			seen[od.data] = true
+		}
+		gs.close()
+	}()
+
+	return gs
+}
+
+// DedupConsecutive returns a stream consisting of the elements of stream
+// with consecutive duplicates (according to ==) removed. Unlike Distinct,
+// only the last element is remembered, so it works on infinite streams.
+func DedupConsecutive[T comparable](stream Stream[T]) Stream[T] {
+	s := stream.(*genericStream[T])
+	s.validateState()
+
+	gs := &genericStream[T]{
+		parallelCount: 1,
+		unordered:     s.unordered,
+		stages:        s.stages,
+		failure:       s.failure,
+		cancel:        s.cancel,
+		prevReq:       s.nextReq,
+		prevData:      s.nextData,
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[T]),
+	}
+
+	go func() {
+		var last T
+		first := true
+
+		for gs.getNextReq() {
+			od, ok := gs.getPrevData()
+			if !ok {
+				gs.close()
+				return
+			}
+
+			for !first && od.data == last {
+				od, ok = gs.getPrevData()
+				if !ok {
+					gs.close()
+					return
+				}
+			}
+			gs.nextData <- od
+			last = od.data
+			first = false
+		}
+		gs.close()
+	}()
+
+	return gs
+}
+
+// Union returns a stream consisting of the distinct elements (according to
+// ==) of a followed by the distinct elements of b which don't appear in a.
+// Both a and b are streamed lazily, but all the distinct elements seen are
+// remembered.
+func Union[T comparable](a, b Stream[T]) Stream[T] {
+	return Distinct(Concat(a, b))
+}
+
+// Intersect returns a stream consisting of the distinct elements (according
+// to ==) of a which also appear in b. While a is streamed lazily, all the
+// elements of b are buffered when the first element of a is examined, so b
+// must be finite.
+func Intersect[T comparable](a, b Stream[T]) Stream[T] {
+	set := sync.OnceValue(func() map[T]bool {
+		return CollectByCollector(b, ToSetCollector[T]())
+	})
+
+	return Distinct(a.Filter(func(t T) bool {
+		return set()[t]
+	}))
+}
+
+// Difference returns a stream consisting of the distinct elements
+// (according to ==) of a which don't appear in b. While a is streamed lazily,
+// all the elements of b are buffered when the first element of a is
+// examined, so b must be finite.
+func Difference[T comparable](a, b Stream[T]) Stream[T] {
+	set := sync.OnceValue(func() map[T]bool {
+		return CollectByCollector(b, ToSetCollector[T]())
+	})
+
+	return Distinct(a.Filter(func(t T) bool {
+		return !set()[t]
+	}))
+}
+
+// Window returns a stream consisting of consecutive non-overlapping slices
+// of n elements of stream. The last slice may contain fewer than n elements.
+func Window[T any](stream Stream[T], n int) Stream[[]T] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	if n <= 0 {
+		panic(fmt.Sprintf("n must be positive: %v", n))
+	}
+
+	newGS, getPrevData := newDerivedStream[[]T](gs.inEncounterOrder())
+
+	go func() {
+		order := uint64(0)
+		for newGS.getNextReq() {
+			window := make([]T, 0, n)
+			for len(window) < n {
+				od, ok := getPrevData()
+				if !ok {
+					break
+				}
+				window = append(window, od.data)
+			}
+
+			if len(window) == 0 {
+				break
+			}
+			newGS.nextData <- orderedData[[]T]{
+				order: order,
+				data:  window,
+			}
+			order++
+
+			if len(window) < n {
+				break
+			}
+		}
+		newGS.close()
+	}()
+
+	return newGS
+}
+
+// SlidingWindow returns a stream consisting of overlapping slices of size
+// elements of stream. The first slice starts with the first element of
+// stream, and each following slice starts step elements after the previous
+// one. Only slices of exactly size elements are included.
+func SlidingWindow[T any](stream Stream[T], size, step int) Stream[[]T] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	if size <= 0 {
+		panic(fmt.Sprintf("size must be positive: %v", size))
+	}
+	if step <= 0 {
+		panic(fmt.Sprintf("step must be positive: %v", step))
+	}
+
+	newGS, getPrevData := newDerivedStream[[]T](gs.inEncounterOrder())
+
+	go func() {
+		window := make([]T, 0, size)
+		skip := 0 // the number of elements between windows to be discarded
+		order := uint64(0)
+
+	loop:
+		for newGS.getNextReq() {
+			for ; skip > 0; skip-- {
+				if _, ok := getPrevData(); !ok {
+					break loop
+				}
+			}
+			for len(window) < size {
+				od, ok := getPrevData()
+				if !ok {
+					break loop
+				}
+				window = append(window, od.data)
+			}
+
+			newGS.nextData <- orderedData[[]T]{
+				order: order,
+				data:  slices.Clone(window),
+			}
+			order++
+
+			if step < size {
+				window = append(window[:0], window[step:]...)
+			} else {
+				window = window[:0]
+				skip = step - size
+			}
+		}
+		newGS.close()
+	}()
+
+	return newGS
+}
+
+// ChunkBy returns a stream consisting of slices of consecutive elements of
+// stream which have equal keys extracted by the key function. Unlike
+// GroupingByCollector, elements with equal keys which are not adjacent belong
+// to different slices.
+func ChunkBy[T any, K comparable](
+	stream Stream[T],
+	key function.Function[T, K],
+) Stream[[]T] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
+
+	newGS, getPrevData := newDerivedStream[[]T](gs.inEncounterOrder())
 
 	go func() {
-		i := 0
-		for range nextReq {
-			if i == len(data) {
-				close(nextData)
-				close(prevDone)
-				go func() {
-					for range nextReq {
+		var chunk []T
+		var chunkKey K
+		order := uint64(0)
+
+		for newGS.getNextReq() {
+			for {
+				od, ok := getPrevData()
+				if !ok {
+					if len(chunk) > 0 {
+						newGS.nextData <- orderedData[[]T]{
+							order: order,
+							data:  chunk,
+						}
 					}
-				}()
-				return
-			}
-			if i < len(data) {
-				nextData <- orderedData[T]{
-					order: uint64(i),
-					data:  data[i],
+					newGS.close()
+					return
+				}
+
+				k := key(od.data)
+				if len(chunk) == 0 || k == chunkKey {
+					chunk = append(chunk, od.data)
+					chunkKey = k
+					continue
 				}
-				i++
+
+				// od.data begins the next chunk.
+				newGS.nextData <- orderedData[[]T]{
+					order: order,
+					data:  chunk,
+				}
+				order++
+				chunk = []T{od.data}
+				chunkKey = k
+				break
 			}
 		}
-		close(nextData)
-		close(prevDone)
+		newGS.close()
 	}()
 
-	return &genericStream[T]{
-		parallelCount: 1,
-		prevDone:      prevDone,
-		nextReq:       nextReq,
-		nextData:      nextData,
-	}
+	return newGS
 }
 
-// Distinct returns a stream consisting of the distinct elements
-// (according to ==) of this stream.
-func Distinct[T comparable](stream Stream[T]) Stream[T] {
-	s := stream.(*genericStream[T])
-	s.validateState()
+// ZipWithIndex returns a stream consisting of the elements of stream paired
+// with their indices in encounter order, starting from 0.
+//
+// If stream is parallel, its elements are buffered to determine their
+// indices, so the stream must be finite.
+func ZipWithIndex[T any](stream Stream[T]) Stream[Indexed[T]] {
+	gs := stream.(*genericStream[T])
+	gs.validateState()
 
-	gs := &genericStream[T]{
-		parallelCount: 1,
-		prevReq:       s.nextReq,
-		prevData:      s.nextData,
-		nextReq:       make(chan struct{}),
-		nextData:      make(chan orderedData[T]),
+	if gs.parallel {
+		dataSlice := gs.ToSlice()
+		indexed := make([]Indexed[T], len(dataSlice))
+		for i, data := range dataSlice {
+			indexed[i] = Indexed[T]{Index: int64(i), Value: data}
+		}
+		return ofCollected(gs, indexed).Parallel()
 	}
 
-	go func() {
-		seen := make(map[T]bool)
+	newGS, getPrevData := newDerivedStream[Indexed[T]](gs)
 
-		for range gs.nextReq {
-			od, ok := gs.getPrevData()
+	go func() {
+		index := int64(0)
+		for newGS.getNextReq() {
+			od, ok := getPrevData()
 			if !ok {
-				gs.close()
-				return
+				break
 			}
-
-			for seen[od.data] {
-				od, ok = gs.getPrevData()
-				if !ok {
-					gs.close()
-					return
-				}
+			newGS.nextData <- orderedData[Indexed[T]]{
+				order: od.order,
+				data:  Indexed[T]{Index: index, Value: od.data},
 			}
-			gs.nextData <- od
-			seen[od.data] = true
+			index++
 		}
-		gs.close()
+		newGS.close()
 	}()
 
-	return gs
+	return newGS
+}
+
+// MapIndexed returns a stream consisting of the results of applying the
+// given function to the elements of stream with their indices in encounter
+// order, starting from 0. As ZipWithIndex, if stream is parallel, it must be
+// finite.
+func MapIndexed[T, R any](
+	stream Stream[T],
+	mapper function.IndexedFunction[T, R],
+) Stream[R] {
+	return Map(ZipWithIndex(stream), func(iv Indexed[T]) R {
+		return mapper(iv.Index, iv.Value)
+	})
+}
+
+// FilterIndexed returns a stream consisting of the elements of stream that
+// match the given predicate, which receives the elements with their indices
+// in encounter order, starting from 0. As ZipWithIndex, if stream is
+// parallel, it must be finite.
+func FilterIndexed[T any](
+	stream Stream[T],
+	predicate function.IndexedPredicate[T],
+) Stream[T] {
+	return Map(ZipWithIndex(stream).Filter(func(iv Indexed[T]) bool {
+		return predicate(iv.Index, iv.Value)
+	}), func(iv Indexed[T]) T {
+		return iv.Value
+	})
 }
 
 // Sorted returns a stream consisting of the elements of stream, sorted
-// according to natural order.
+// according to natural order, such as lexicographic order for strings.
 func Sorted[T cmp.Ordered](stream Stream[T]) Stream[T] {
 	s := stream.(*genericStream[T])
 	s.validateState()
@@ -229,16 +885,47 @@ func Sorted[T cmp.Ordered](stream Stream[T]) Stream[T] {
 	}
 	close(prevReq)
 
-	slices.SortFunc(dataSlice, func(a, b T) int {
-		if a == b {
-			return 0
+	// cmp.Compare orders NaNs before other values consistently.
+	slices.SortFunc(dataSlice, cmp.Compare[T])
+	return ofCollected(s, dataSlice)
+}
+
+// SortedBy returns a stream consisting of the elements of stream, sorted
+// according to natural order of the keys extracted by the key function.
+// Elements with equal keys are further sorted by thenBy comparison functions
+// in turn, such as ones returned by ThenBy. The sort is stable: elements
+// which compare equal keep their encounter order.
+func SortedBy[T any, K cmp.Ordered](
+	stream Stream[T],
+	key function.Function[T, K],
+	thenBy ...func(a, b T) int,
+) Stream[T] {
+	gs := stream.(*genericStream[T])
+	dataSlice := gs.ToSlice()
+
+	slices.SortStableFunc(dataSlice, func(a, b T) int {
+		if c := cmp.Compare(key(a), key(b)); c != 0 {
+			return c
 		}
-		if a < b {
-			return -1
+		for _, then := range thenBy {
+			if c := then(a, b); c != 0 {
+				return c
+			}
 		}
-		return 1
+		return 0
 	})
-	return Of(dataSlice...)
+	return ofCollected(gs, dataSlice)
+}
+
+// ThenBy returns a comparison function which compares elements by natural
+// order of the keys extracted by the key function. It is intended to be
+// passed to SortedBy for the secondary keys.
+func ThenBy[T any, K cmp.Ordered](
+	key function.Function[T, K],
+) func(a, b T) int {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
 }
 
 // Reduce performs a reduction on the elements of stream, using the provided
@@ -334,36 +1021,210 @@ func Collect[R, T any](
 // arguments to Collect(Supplier, BiConsumer, BiConsumer), allowing for
 // resuse of collection strategies and composition of collect operations such
 // as multiple-level grouping or partitioning.
+//
+// The Characteristics of the collector are used as follows for a parallel
+// stream:
+//   - If the collector is Concurrent, and either it is Unordered or the
+//     stream is unordered, all elements are accumulated into a single shared
+//     result container.
+//   - If neither the collector nor the stream is Unordered, the elements are
+//     accumulated into result containers for contiguous runs of elements,
+//     which are combined in encounter order.
+//   - Otherwise, the elements are accumulated into a result container for
+//     each worker, which are combined in unspecified order.
+//
+// If the collector has IdentityFinish, its finisher is not called.
 func CollectByCollector[T, R, A any](
 	stream Stream[T],
 	collector *Collector[T, A, R],
 ) R {
-	supplier := collector.Supplier()
-	accumulator := collector.Accumulator()
-	combiner := func(r, t A) {
-		_ = collector.Combiner()(r, t)
+	s := stream.(*genericStream[T])
+	characteristics := collector.Characteristics()
+	unordered := characteristics.Has(Unordered) || s.unordered
+
+	var a A
+	switch {
+	case s.parallelCount > 1 && characteristics.Has(Concurrent) && unordered:
+		a = collectConcurrent(s, collector)
+	case s.parallelCount > 1 && !unordered:
+		a = collectOrdered(s, collector)
+	default:
+		supplier := collector.Supplier()
+		accumulator := collector.Accumulator()
+		combiner := func(r, t A) {
+			_ = collector.Combiner()(r, t)
+		}
+		a = Collect(stream, supplier, accumulator, combiner)
 	}
 
-	a := Collect(stream, supplier, accumulator, combiner)
+	if characteristics.Has(IdentityFinish) {
+		return any(a).(R)
+	}
 	return collector.Finisher()(a)
 }
 
-// Empty returns an empty Stream
-func Empty[T any]() Stream[T] {
-	gs := &genericStream[T]{
-		parallelCount: 1,
-		nextReq:       make(chan struct{}),
-		nextData:      make(chan orderedData[T]),
+// collectConcurrent accumulates all elements of s into a single result
+// container shared by the workers.
+func collectConcurrent[T, R, A any](
+	s *genericStream[T],
+	collector *Collector[T, A, R],
+) A {
+	s.validateState()
+
+	prevReq := s.nextReq
+	prevData := s.nextData
+
+	result := collector.Supplier()()
+	accumulator := collector.Accumulator()
+
+	parallelCount := s.parallelCount
+	var wg sync.WaitGroup
+	for i := 0; i < parallelCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				prevReq <- struct{}{}
+				od, ok := <-prevData
+				if !ok {
+					break
+				}
+				accumulator(result, od.data)
+			}
+		}()
 	}
+	wg.Wait()
 
-	go func() {
-		for range gs.nextReq {
-			// discard all requests
+	close(prevReq)
+
+	return result
+}
+
+// collectOrdered accumulates all elements of s in encounter order. If s is
+// split-based, the elements of each part are accumulated into a result
+// container by a worker. Otherwise, each worker accumulates each run of the
+// elements of consecutive orders it gets into a result container keyed by
+// the order of the first element. The results are combined in order.
+func collectOrdered[T, R, A any](
+	s *genericStream[T],
+	collector *Collector[T, A, R],
+) A {
+	supplier := collector.Supplier()
+	accumulator := collector.Accumulator()
+	combiner := collector.Combiner()
+
+	// the parts are contiguous, so their results are combined in order.
+	results := make([]A, s.parallelCount)
+	if s.runSplit(func(k int, part pullFunc[T]) {
+		result := supplier()
+		for od, ok := part(); ok; od, ok = part() {
+			accumulator(result, od.data)
 		}
-	}()
+		results[k] = result
+	}) {
+		result := results[0]
+		for _, r := range results[1:] {
+			result = combiner(result, r)
+		}
+		return result
+	}
 
-	close(gs.nextData)
-	return gs
+	s.validateState()
+
+	type run struct {
+		first  uint64 // the order of the first element
+		next   uint64 // the order following the last element
+		result A
+	}
+
+	runs := make(chan []run)
+
+	parallelCount := s.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			var rs []run
+
+			s.terminalOpOrderedData(func(od orderedData[T]) {
+				if len(rs) == 0 || rs[len(rs)-1].next != od.order {
+					rs = append(rs, run{first: od.order, result: supplier()})
+				}
+				r := &rs[len(rs)-1]
+				accumulator(r.result, od.data)
+				r.next = od.order + 1
+			})
+
+			runs <- rs
+		}()
+	}
+
+	var all []run
+	for i := 0; i < parallelCount; i++ {
+		all = append(all, <-runs...)
+	}
+	close(runs)
+
+	if len(all) == 0 {
+		return supplier()
+	}
+
+	slices.SortFunc(all, func(a, b run) int {
+		return cmp.Compare(a.first, b.first)
+	})
+	result := all[0].result
+	for _, r := range all[1:] {
+		result = combiner(result, r.result)
+	}
+	return result
+}
+
+// ToMap returns a map whose keys and values are the result of applying the
+// provided mapping functions to the elements of stream.
+//
+// If the mapped keys contains duplicates, this function panics.
+func ToMap[T any, K comparable, V any](
+	stream Stream[T],
+	keyMapper function.Function[T, K],
+	valueMapper function.Function[T, V],
+) map[K]V {
+	return CollectByCollector(stream,
+		ToUniqueKeysMapCollector(keyMapper, valueMapper))
+}
+
+// ToSet returns a set of the distinct elements of stream.
+func ToSet[T comparable](stream Stream[T]) map[T]struct{} {
+	return Collect(stream,
+		func() map[T]struct{} {
+			return make(map[T]struct{})
+		},
+		func(m map[T]struct{}, t T) {
+			m[t] = struct{}{}
+		},
+		func(m1, m2 map[T]struct{}) {
+			for t := range m2 {
+				m1[t] = struct{}{}
+			}
+		})
+}
+
+// GroupBy returns a map whose keys are the result of applying the classifier
+// function to the elements of stream, and whose values are the slices of the
+// elements mapped to the keys, in encounter order.
+func GroupBy[T any, K comparable](
+	stream Stream[T],
+	classifier function.Function[T, K],
+) map[K][]T {
+	groups := make(map[K][]T)
+	for _, t := range stream.ToSlice() {
+		key := classifier(t)
+		groups[key] = append(groups[key], t)
+	}
+	return groups
+}
+
+// Empty returns an empty Stream
+func Empty[T any]() Stream[T] {
+	return Of[T]()
 }
 
 // Iterate returns an infinite sequential ordered Stream produces by iterative
@@ -372,6 +1233,8 @@ func Empty[T any]() Stream[T] {
 func Iterate[T any](seed T, f function.UnaryOperator[T]) Stream[T] {
 	gs := &genericStream[T]{
 		parallelCount: 1,
+		dense:         true,
+		cancel:        newCanceler(),
 		nextReq:       make(chan struct{}, goMaxProcs),
 		nextData:      make(chan orderedData[T], goMaxProcs),
 	}
@@ -381,7 +1244,7 @@ func Iterate[T any](seed T, f function.UnaryOperator[T]) Stream[T] {
 		nextValue := seed
 
 		order := uint64(0)
-		for range gs.nextReq {
+		for gs.getNextReq() {
 			if useSeed {
 				gs.nextData <- orderedData[T]{
 					order: order,
@@ -398,6 +1261,7 @@ func Iterate[T any](seed T, f function.UnaryOperator[T]) Stream[T] {
 			order++
 		}
 		close(gs.nextData)
+		gs.discard(gs.nextReq)
 	}()
 
 	return gs
@@ -414,6 +1278,8 @@ func IterateN[T any](
 
 	gs := &genericStream[T]{
 		parallelCount: 1,
+		dense:         true,
+		cancel:        newCanceler(),
 		nextReq:       make(chan struct{}),
 		nextData:      make(chan orderedData[T]),
 	}
@@ -423,7 +1289,7 @@ func IterateN[T any](
 		applyNext := false
 
 		order := uint64(0)
-		for range gs.nextReq {
+		for gs.getNextReq() {
 			if applyNext {
 				nextValue = next(nextValue)
 			}
@@ -440,6 +1306,7 @@ func IterateN[T any](
 			applyNext = true
 		}
 		close(gs.nextData)
+		gs.discard(gs.nextReq)
 	}()
 
 	return gs
@@ -451,13 +1318,15 @@ func IterateN[T any](
 func Generate[T any](s function.Supplier[T]) Stream[T] {
 	gs := &genericStream[T]{
 		parallelCount: 1,
+		dense:         true,
+		cancel:        newCanceler(),
 		nextReq:       make(chan struct{}),
 		nextData:      make(chan orderedData[T]),
 	}
 
 	go func() {
 		order := uint64(0)
-		for range gs.nextReq {
+		for gs.getNextReq() {
 			gs.nextData <- orderedData[T]{
 				order: order,
 				data:  s(),
@@ -465,70 +1334,169 @@ func Generate[T any](s function.Supplier[T]) Stream[T] {
 			order++
 		}
 		close(gs.nextData)
+		gs.discard(gs.nextReq)
 	}()
 
 	return gs
 }
 
-// Concat a lazily concatenated stream whose elements are all the elements of
-// the first stream followed by all the elements of the second stream.
-func Concat[T any](a, b Stream[T]) Stream[T] {
-	ags := a.(*genericStream[T])
-	bgs := b.(*genericStream[T])
-	ags.validateState()
-	bgs.validateState()
+// Concat returns a lazily concatenated stream whose elements are all the
+// elements of the first stream followed by all the elements of the second
+// stream, and so on. If no stream is given, an empty stream is returned.
+func Concat[T any](streams ...Stream[T]) Stream[T] {
+	sources := make([]*genericStream[T], len(streams))
+	for i, s := range streams {
+		sources[i] = s.(*genericStream[T])
+		sources[i].validateState()
+
+		// the elements of each stream are concatenated in encounter order.
+		sources[i] = sources[i].inEncounterOrder()
+	}
+
+	if len(sources) == 0 {
+		return Empty[T]()
+	}
 
-	// The concatenated stream is always not parallel.
+	// The concatenated stream is always not parallel, and its elements are
+	// numbered consecutively in encounter order.
 	gs := &genericStream[T]{
 		parallelCount: 1,
+		dense:         true,
+		prevReq:       sources[0].nextReq,
+		prevData:      sources[0].nextData,
 		nextReq:       make(chan struct{}),
 		nextData:      make(chan orderedData[T]),
 	}
+	for _, s := range sources {
+		if s.failure != nil {
+			gs.failure = s.failure
+			break
+		}
+	}
+	cancels := make([]*canceler, len(sources))
+	for i, s := range sources {
+		cancels[i] = s.cancel
+	}
+	gs.cancel = newCanceler(cancels...)
 
 	go func() {
-		gs.prevReq = ags.nextReq
-		gs.prevData = ags.nextData
-		switchedToB := false
-
-		offset := uint64(0)
-		lastOrder := uint64(0)
+		current := 0
+		order := uint64(0)
 
-		for range gs.nextReq {
+		for gs.getNextReq() {
 			data, ok := gs.getPrevData()
-			if !ok {
-				if switchedToB {
+			for !ok {
+				if current == len(sources)-1 {
 					gs.close()
 					return
 				}
 
-				gs.prevReq = bgs.nextReq
-				gs.prevData = bgs.nextData
-				switchedToB = true
-				offset = lastOrder + 1
+				close(gs.prevReq)
+				current++
+				gs.prevReq = sources[current].nextReq
+				gs.prevData = sources[current].nextData
 
 				data, ok = gs.getPrevData()
-				if !ok {
-					gs.close()
-					return
-				}
 			}
-			lastOrder = data.order
+
 			gs.nextData <- orderedData[T]{
-				order: data.order + offset,
+				order: order,
 				data:  data.data,
 			}
+			order++
+		}
+
+		// release streams which have not been consumed yet.
+		for _, s := range sources[current+1:] {
+			close(s.nextReq)
+		}
+		gs.close()
+	}()
+
+	return gs
+}
+
+// MergeSorted returns a lazily merged stream whose elements are all the
+// elements of a and b, sorted according to cmp. Both a and b must be already
+// sorted according to cmp in the order their elements are produced, so they
+// should be sequential streams. When elements of a and b compare equal, the
+// element of a comes first.
+func MergeSorted[T any](a, b Stream[T], cmp func(a, b T) int) Stream[T] {
+	ags := a.(*genericStream[T])
+	bgs := b.(*genericStream[T])
+	ags.validateState()
+	bgs.validateState()
+
+	// The merged stream is always not parallel.
+	gs := &genericStream[T]{
+		parallelCount: 1,
+		cancel:        newCanceler(ags.cancel, bgs.cancel),
+		prevReq:       ags.nextReq,
+		prevData:      ags.nextData,
+		nextReq:       make(chan struct{}),
+		nextData:      make(chan orderedData[T]),
+	}
+
+	getDataOfB := func() (orderedData[T], bool) {
+		bgs.nextReq <- struct{}{}
+		data, ok := <-bgs.nextData
+		return data, ok
+	}
+
+	go func() {
+		// The head elements of a and b are fetched only when needed.
+		var headA, headB orderedData[T]
+		var okA, okB bool
+		fetchedA, fetchedB := false, false
+
+		order := uint64(0)
+		for gs.getNextReq() {
+			if !fetchedA {
+				headA, okA = gs.getPrevData()
+				fetchedA = true
+			}
+			if !fetchedB {
+				headB, okB = getDataOfB()
+				fetchedB = true
+			}
+
+			var data T
+			switch {
+			case !okA && !okB:
+				close(bgs.nextReq)
+				gs.close()
+				return
+			case !okA || (okB && cmp(headB.data, headA.data) < 0):
+				data = headB.data
+				fetchedB = false
+			default:
+				data = headA.data
+				fetchedA = false
+			}
+
+			gs.nextData <- orderedData[T]{
+				order: order,
+				data:  data,
+			}
+			order++
 		}
+		close(bgs.nextReq)
 		gs.close()
 	}()
 
 	return gs
 }
 
-// Returns the sum of elements in this stream.
+// Returns the sum of elements in this stream. For floating-point elements,
+// compensated (Kahan) summation is used to reduce the rounding error.
 func Sum[T Number](stream Stream[T]) T {
 	gs := stream.(*genericStream[T])
 	gs.validateState()
 
+	if isFloat[T]() {
+		return T(sumFloat(gs))
+	}
+
 	if !gs.parallel {
 		var sum T
 		gs.terminalOp(func(t T) {
@@ -557,18 +1525,99 @@ func Sum[T Number](stream Stream[T]) T {
 	return sum
 }
 
+// sumFloat returns the sum of the elements of gs using compensated
+// summation.
+func sumFloat[T Number](gs *genericStream[T]) float64 {
+	sums := make(chan *compensatedSum)
+	parallelCount := gs.parallelCount
+	for i := 0; i < parallelCount; i++ {
+		go func() {
+			var sum compensatedSum
+			gs.terminalOp(func(t T) {
+				sum.add(float64(t))
+			})
+			sums <- &sum
+		}()
+	}
+
+	var sum compensatedSum
+	for i := 0; i < parallelCount; i++ {
+		sum.combine(<-sums)
+	}
+	close(sums)
+	return sum.value()
+}
+
+// Average returns an Optional describing the arithmetic mean of the elements
+// of stream, or an empty Optional if stream is empty.
+func Average[T Number](stream Stream[T]) *Optional[float64] {
+	type averaging struct {
+		sum   compensatedSum
+		count int64
+	}
+
+	a := Collect(stream,
+		func() *averaging {
+			return &averaging{}
+		},
+		func(a *averaging, t T) {
+			a.sum.add(float64(t))
+			a.count++
+		},
+		func(a, b *averaging) {
+			a.sum.combine(&b.sum)
+			a.count += b.count
+		})
+
+	if a.count == 0 {
+		return OptionalEmpty[float64]()
+	}
+	return OptionalOf(a.sum.value() / float64(a.count))
+}
+
+// Summarize returns summary statistics, such as count, sum, min, max and
+// average, of the elements of stream.
+func Summarize[T Number](stream Stream[T]) *SummaryStatistics[T] {
+	return CollectByCollector(stream, SummarizingCollector(Identity[T]))
+}
+
 // Range returns a sequential ordered Stream from startInclusive to
 // endExclusive (exclusive) by an incremental step of 1.
 func Range[T Number](
 	startInclusive T,
 	endExclusive T,
 ) Stream[T] {
-	return Iterate(
-		startInclusive,
-		func(t T) T {
-			return t + 1
-		},
-	).Limit(int(endExclusive - startInclusive))
+	return RangeBy(startInclusive, endExclusive, 1)
+}
+
+// RangeBy returns a sequential ordered Stream from startInclusive to
+// endExclusive (exclusive) by an incremental step. The step may be negative
+// or fractional, but must not be zero. If endExclusive can't be reached from
+// startInclusive by the step, an empty Stream is returned.
+//
+// The i-th element is computed as startInclusive + i*step, so that
+// rounding errors of fractional steps are not accumulated.
+func RangeBy[T Number](
+	startInclusive T,
+	endExclusive T,
+	step T,
+) Stream[T] {
+	if step == 0 {
+		panic("step must not be zero")
+	}
+
+	// The number of elements is computed in float64 so that the
+	// subtraction doesn't overflow for unsigned types.
+	n := math.Ceil(
+		(float64(endExclusive) - float64(startInclusive)) / float64(step))
+	if !(n > 0) {
+		return Empty[T]()
+	}
+	count := uint64(n)
+
+	return newSizedStream(count, func(i uint64) T {
+		return startInclusive + T(i)*step
+	})
 }
 
 // RangeClosed returns a sequential ordered Stream from staticInclusive to
@@ -577,16 +1626,20 @@ func RangeClosed[T Number](
 	startInclusive T,
 	endInclusive T,
 ) Stream[T] {
-	return Iterate(
-		startInclusive,
-		func(t T) T {
-			return t + 1
-		},
-	).Limit(int(endInclusive - startInclusive + 1))
+	if endInclusive < startInclusive {
+		return Empty[T]()
+	}
+
+	// The number of elements is computed in float64 as RangeBy does.
+	count := uint64(float64(endInclusive)-float64(startInclusive)) + 1
+	return newSizedStream(count, func(i uint64) T {
+		return startInclusive + T(i)
+	})
 }
 
-// Max returns the maximum element of a stream.
-func Max[T Number](
+// Max returns the maximum element of a stream according to natural order,
+// such as lexicographic order for strings.
+func Max[T cmp.Ordered](
 	stream Stream[T],
 ) *Optional[T] {
 	return stream.Max(func(x, y T) bool {
@@ -594,8 +1647,9 @@ func Max[T Number](
 	})
 }
 
-// Min returns the minimum element of a stream.
-func Min[T Number](
+// Min returns the minimum element of a stream according to natural order,
+// such as lexicographic order for strings.
+func Min[T cmp.Ordered](
 	stream Stream[T],
 ) *Optional[T] {
 	return stream.Min(func(x, y T) bool {
@@ -1,15 +1,22 @@
-// Copyright © 2020, 2021 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2021, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/YoshikiShibata/gostream/function"
 )
 
 func TestStream_MapFunc(t *testing.T) {
@@ -43,6 +50,320 @@ func TestStream_MapFunc(t *testing.T) {
 	}
 }
 
+func TestStream_MapFunc_Compose(t *testing.T) {
+	normalize := function.Function[string, string](strings.TrimSpace).
+		AndThen(strings.ToLower)
+	length := function.Compose(normalize, func(s string) int {
+		return len(s)
+	})
+
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[string] {
+			s := Of(" Go ", "JAVA", "  Rust")
+			if parallel {
+				s = s.Parallel()
+			}
+			return s
+		}
+
+		result := Map(newStream(), normalize).ToSlice()
+		want := []string{"go", "java", "rust"}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+
+		lengths := Map(newStream(), length).ToSlice()
+		wantLengths := []int{2, 4, 4}
+		if !slices.Equal(lengths, wantLengths) {
+			t.Errorf("[%v] lengths is %v, want %v", parallel, lengths, wantLengths)
+		}
+	}
+}
+
+func TestStream_MapOrSkipFunc(t *testing.T) {
+	parse := func(s string) *Optional[int] {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return OptionalEmpty[int]()
+		}
+		return OptionalOf(v)
+	}
+
+	for _, tc := range [...]struct {
+		dataSize int
+	}{
+		{dataSize: 0},
+		{dataSize: 1},
+		{dataSize: 1000},
+	} {
+		var data []string
+		var want []int
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, strconv.Itoa(i), "x"+strconv.Itoa(i))
+			want = append(want, i)
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := MapOrSkip(s, parse).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+}
+
+func TestStream_FilterPresentFunc(t *testing.T) {
+	data := []*Optional[int]{
+		OptionalOf(1), OptionalEmpty[int](), OptionalOf(2), OptionalEmpty[int](),
+		OptionalOf(3),
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(data...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := FilterPresent(s).ToSlice()
+		want := []int{1, 2, 3}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestStream_OfTypeFunc(t *testing.T) {
+	events := []any{1, "two", 3.0, 4, nil, fmt.Errorf("five"), "six"}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(events...)
+		if parallel {
+			s = s.Parallel()
+		}
+		ints := OfType[int](s).ToSlice()
+		if want := []int{1, 4}; !slices.Equal(ints, want) {
+			t.Errorf("ints is %v, want %v", ints, want)
+		}
+	}
+
+	strs := OfType[string](Of(events...)).ToSlice()
+	if want := []string{"two", "six"}; !slices.Equal(strs, want) {
+		t.Errorf("strs is %v, want %v", strs, want)
+	}
+
+	errs := OfType[error](Of(events...)).ToSlice()
+	if len(errs) != 1 || errs[0].Error() != "five" {
+		t.Errorf("errs is %v, want [five]", errs)
+	}
+
+	stringers := OfType[fmt.Stringer](Of(
+		Indexed[int]{Index: 0, Value: 1}, Indexed[int]{Index: 1, Value: 2},
+	)).ToSlice()
+	if len(stringers) != 2 {
+		t.Errorf("len(stringers) is %d, want 2", len(stringers))
+	}
+}
+
+func TestStream_MapErrFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Of("1", "2", "3")
+		if parallel {
+			s = s.Parallel()
+		}
+
+		mapped := MapErr(s, strconv.Atoi)
+		result := mapped.ToSlice()
+		if !slices.Equal(result, []int{1, 2, 3}) {
+			t.Errorf("[%v] result is %v, want [1 2 3]", parallel, result)
+		}
+		if mapped.Err() != nil {
+			t.Errorf("[%v] mapped.Err() is %v, want nil", parallel, mapped.Err())
+		}
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of("1", "2", "x", "4")
+		if parallel {
+			s = s.Parallel()
+		}
+
+		mapped := MapErr(s, strconv.Atoi)
+		result := mapped.ToSlice()
+		if !parallel && !slices.Equal(result, []int{1, 2}) {
+			t.Errorf("result is %v, want [1 2]", result)
+		}
+
+		var numErr *strconv.NumError
+		if !errors.As(mapped.Err(), &numErr) {
+			t.Errorf("[%v] mapped.Err() is %v, want *strconv.NumError",
+				parallel, mapped.Err())
+		}
+	}
+
+	t.Run("TryForEach", func(t *testing.T) {
+		err := MapErr(Iterate(0, func(v int) int {
+			return v + 1
+		}).Parallel(), func(v int) (int, error) {
+			if v == 100 {
+				return 0, fmt.Errorf("bad value: %d", v)
+			}
+			return v, nil
+		}).TryForEach(func(v int) error {
+			return nil
+		})
+		if err == nil || err.Error() != "bad value: 100" {
+			t.Errorf("err is %v, want bad value: 100", err)
+		}
+	})
+}
+
+func TestStream_FilterErrFunc(t *testing.T) {
+	errNegative := errors.New("negative")
+	isEven := func(v int) (bool, error) {
+		if v < 0 {
+			return false, errNegative
+		}
+		return v%2 == 0, nil
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 10)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		filtered := FilterErr(s, isEven)
+		result := filtered.ToSlice()
+		if !slices.Equal(result, []int{0, 2, 4, 6, 8}) {
+			t.Errorf("[%v] result is %v, want [0 2 4 6 8]", parallel, result)
+		}
+		if filtered.Err() != nil {
+			t.Errorf("[%v] filtered.Err() is %v, want nil",
+				parallel, filtered.Err())
+		}
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of(0, 1, 2, -1, 4)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		filtered := FilterErr(s, isEven)
+		result := filtered.ToSlice()
+		if !parallel && !slices.Equal(result, []int{0, 2}) {
+			t.Errorf("result is %v, want [0 2]", result)
+		}
+		if filtered.Err() != errNegative {
+			t.Errorf("[%v] filtered.Err() is %v, want %v",
+				parallel, filtered.Err(), errNegative)
+		}
+	}
+}
+
+func TestStream_MapWithTimeoutFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		mapped := MapWithTimeout(s, time.Minute, strconv.Itoa)
+		result := mapped.ToSlice()
+		if len(result) != 100 {
+			t.Errorf("[%v] len(result) is %d, want 100", parallel, len(result))
+		}
+		if mapped.Err() != nil {
+			t.Errorf("[%v] mapped.Err() is %v, want nil", parallel, mapped.Err())
+		}
+	}
+
+	// the mapping of 3 is blocked until the test ends.
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		mapped := MapWithTimeout(s, 100*time.Millisecond, func(v int) int {
+			if v == 3 {
+				<-blocked
+			}
+			return v
+		})
+		result := mapped.ToSlice()
+		if slices.Contains(result, 3) {
+			t.Errorf("[%v] result %v contains 3", parallel, result)
+		}
+		if !parallel && !slices.Equal(result, []int{0, 1, 2}) {
+			t.Errorf("result is %v, want [0 1 2]", result)
+		}
+		if mapped.Err() != ErrTimeout {
+			t.Errorf("[%v] mapped.Err() is %v, want %v",
+				parallel, mapped.Err(), ErrTimeout)
+		}
+	}
+}
+
+func TestStream_MapConcurrentFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		n        int
+	}{
+		{dataSize: 0, n: 1},
+		{dataSize: 1, n: 1},
+		{dataSize: 100, n: 1},
+		{dataSize: 100, n: 10},
+	} {
+		var data []int
+		var want []string
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, i)
+			want = append(want, strconv.Itoa(i))
+		}
+
+		var running, maxRunning int64
+		mapper := func(v int) string {
+			r := atomic.AddInt64(&running, 1)
+			for {
+				m := atomic.LoadInt64(&maxRunning)
+				if r <= m || atomic.CompareAndSwapInt64(&maxRunning, m, r) {
+					break
+				}
+			}
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+			atomic.AddInt64(&running, -1)
+			return strconv.Itoa(v)
+		}
+
+		result := MapConcurrent(Of(data...), tc.n, mapper).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+		if maxRunning > int64(tc.n) {
+			t.Errorf("maxRunning is %d, want <= %d", maxRunning, tc.n)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		result := MapConcurrent(Iterate(0, func(v int) int {
+			return v + 1
+		}), 4, strconv.Itoa).Limit(10).ToSlice()
+		want := Map(Range(0, 10), strconv.Itoa).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
 func TestStream_DistinctFunc(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
@@ -74,6 +395,387 @@ func TestStream_DistinctFunc(t *testing.T) {
 	}
 }
 
+// version is a Comparable type for tests of the Comparable functions.
+type version struct {
+	major, minor int
+	label        string // not compared
+}
+
+func (v version) CompareTo(o version) int {
+	if c := cmp.Compare(v.major, o.major); c != 0 {
+		return c
+	}
+	return cmp.Compare(v.minor, o.minor)
+}
+
+func TestStream_ComparableFunc(t *testing.T) {
+	data := []version{
+		{1, 2, "a"}, {0, 9, "b"}, {1, 10, "c"}, {1, 2, "d"}, {0, 9, "e"},
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[version] {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+			return s
+		}
+
+		result := SortedComparable(newStream()).ToSlice()
+		want := []version{
+			{0, 9, "b"}, {0, 9, "e"}, {1, 2, "a"}, {1, 2, "d"}, {1, 10, "c"},
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] SortedComparable is %v, want %v", parallel, result, want)
+		}
+
+		result = DistinctComparable(newStream()).ToSlice()
+		want = []version{{1, 2, "a"}, {0, 9, "b"}, {1, 10, "c"}}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] DistinctComparable is %v, want %v", parallel, result, want)
+		}
+
+		min := MinComparable(newStream()).Get()
+		if min.CompareTo(version{0, 9, ""}) != 0 {
+			t.Errorf("[%v] MinComparable is %v, want 0.9", parallel, min)
+		}
+
+		max := MaxComparable(newStream()).Get()
+		if max != (version{1, 10, "c"}) {
+			t.Errorf("[%v] MaxComparable is %v, want 1.10", parallel, max)
+		}
+	}
+
+	if MaxComparable(Empty[version]()).IsPresent() {
+		t.Errorf("MaxComparable of empty stream is present")
+	}
+
+	t.Run("reordered", func(t *testing.T) {
+		// each version is labeled with its position.
+		toVersion := func(v int) version {
+			return version{0, v % 10, strconv.Itoa(v)}
+		}
+
+		result := DistinctComparable(Map(jittered(200), toVersion)).ToSlice()
+		want := Map(Range(0, 10), toVersion).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("DistinctComparable is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_DedupConsecutiveFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []int
+		want []int
+	}{
+		{data: nil, want: nil},
+		{data: []int{0}, want: []int{0}},
+		{data: []int{0, 0, 0}, want: []int{0}},
+		{data: []int{1, 1, 2, 3, 3, 3, 1, 2, 2}, want: []int{1, 2, 3, 1, 2}},
+	} {
+		result := DedupConsecutive(Of(tc.data...)).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("DedupConsecutive(%v) is %v, want %v",
+				tc.data, result, tc.want)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		tens := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}), func(v int) int {
+			return v / 10
+		})
+
+		result := DedupConsecutive(tens).Limit(3).ToSlice()
+		want := []int{0, 1, 2}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_SetOperationFuncs(t *testing.T) {
+	for _, tc := range [...]struct {
+		a, b       []int
+		union      []int
+		intersect  []int
+		difference []int
+	}{
+		{a: nil, b: nil, union: nil, intersect: nil, difference: nil},
+		{a: []int{1, 2}, b: nil,
+			union: []int{1, 2}, intersect: nil, difference: []int{1, 2}},
+		{a: nil, b: []int{1, 2},
+			union: []int{1, 2}, intersect: nil, difference: nil},
+		{a: []int{5, 1, 3, 1, 4}, b: []int{4, 2, 5, 2},
+			union:      []int{5, 1, 3, 4, 2},
+			intersect:  []int{5, 4},
+			difference: []int{1, 3}},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			stream := func(data []int) Stream[int] {
+				s := Of(data...)
+				if parallel {
+					s = s.Parallel()
+				}
+				return s
+			}
+
+			result := Union(stream(tc.a), stream(tc.b)).ToSlice()
+			if !slices.Equal(result, tc.union) {
+				t.Errorf("Union(%v, %v) is %v, want %v",
+					tc.a, tc.b, result, tc.union)
+			}
+
+			result = Intersect(stream(tc.a), stream(tc.b)).ToSlice()
+			if !slices.Equal(result, tc.intersect) {
+				t.Errorf("Intersect(%v, %v) is %v, want %v",
+					tc.a, tc.b, result, tc.intersect)
+			}
+
+			result = Difference(stream(tc.a), stream(tc.b)).ToSlice()
+			if !slices.Equal(result, tc.difference) {
+				t.Errorf("Difference(%v, %v) is %v, want %v",
+					tc.a, tc.b, result, tc.difference)
+			}
+		}
+	}
+}
+
+func TestStream_WindowFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		n        int
+		want     [][]int
+	}{
+		{dataSize: 0, n: 2, want: nil},
+		{dataSize: 1, n: 2, want: [][]int{{0}}},
+		{dataSize: 4, n: 2, want: [][]int{{0, 1}, {2, 3}}},
+		{dataSize: 5, n: 2, want: [][]int{{0, 1}, {2, 3}, {4}}},
+		{dataSize: 3, n: 1, want: [][]int{{0}, {1}, {2}}},
+	} {
+		result := Window(Range(0, tc.dataSize), tc.n).ToSlice()
+		if !slices.EqualFunc(result, tc.want, slices.Equal[[]int]) {
+			t.Errorf("result is %v, want %v", result, tc.want)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		result := Window(Iterate(0, func(v int) int {
+			return v + 1
+		}), 3).Limit(2).ToSlice()
+		want := [][]int{{0, 1, 2}, {3, 4, 5}}
+		if !slices.EqualFunc(result, want, slices.Equal[[]int]) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		var result []int
+		for _, window := range Window(jittered(200), 7).ToSlice() {
+			result = append(result, window...)
+		}
+		if want := Range(0, 200).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_SlidingWindowFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize   int
+		size, step int
+		want       [][]int
+	}{
+		{dataSize: 0, size: 2, step: 1, want: nil},
+		{dataSize: 1, size: 2, step: 1, want: nil},
+		{dataSize: 4, size: 2, step: 1, want: [][]int{{0, 1}, {1, 2}, {2, 3}}},
+		{dataSize: 6, size: 3, step: 2, want: [][]int{{0, 1, 2}, {2, 3, 4}}},
+		{dataSize: 6, size: 2, step: 2, want: [][]int{{0, 1}, {2, 3}, {4, 5}}},
+		{dataSize: 8, size: 2, step: 3, want: [][]int{{0, 1}, {3, 4}, {6, 7}}},
+	} {
+		result := SlidingWindow(Range(0, tc.dataSize), tc.size, tc.step).ToSlice()
+		if !slices.EqualFunc(result, tc.want, slices.Equal[[]int]) {
+			t.Errorf("SlidingWindow(Range(0, %d), %d, %d) is %v, want %v",
+				tc.dataSize, tc.size, tc.step, result, tc.want)
+		}
+	}
+
+	t.Run("moving average", func(t *testing.T) {
+		averages := Map(SlidingWindow(Iterate(1, func(v int) int {
+			return v + 1
+		}), 3, 1), func(w []int) int {
+			return (w[0] + w[1] + w[2]) / 3
+		}).Limit(5).ToSlice()
+		want := []int{2, 3, 4, 5, 6}
+		if !slices.Equal(averages, want) {
+			t.Errorf("averages is %v, want %v", averages, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		result := SlidingWindow(jittered(200), 2, 1).ToSlice()
+		if len(result) != 199 {
+			t.Fatalf("len(result) is %d, want 199", len(result))
+		}
+		for i, window := range result {
+			if want := []int{i, i + 1}; !slices.Equal(window, want) {
+				t.Errorf("result[%d] is %v, want %v", i, window, want)
+			}
+		}
+	})
+}
+
+func TestStream_ChunkByFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []string
+		want [][]string
+	}{
+		{data: nil, want: nil},
+		{data: []string{"a"}, want: [][]string{{"a"}}},
+		{
+			data: []string{"apple", "avocado", "banana", "blueberry", "cherry", "apricot"},
+			want: [][]string{
+				{"apple", "avocado"},
+				{"banana", "blueberry"},
+				{"cherry"},
+				{"apricot"},
+			},
+		},
+	} {
+		result := ChunkBy(Of(tc.data...), func(s string) byte {
+			return s[0]
+		}).ToSlice()
+		if !slices.EqualFunc(result, tc.want, slices.Equal[[]string]) {
+			t.Errorf("result is %v, want %v", result, tc.want)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		result := ChunkBy(Iterate(0, func(v int) int {
+			return v + 1
+		}), func(v int) int {
+			return v / 3
+		}).Limit(2).ToSlice()
+		want := [][]int{{0, 1, 2}, {3, 4, 5}}
+		if !slices.EqualFunc(result, want, slices.Equal[[]int]) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		result := ChunkBy(jittered(200), func(v int) int {
+			return v / 10
+		}).ToSlice()
+		want := Window(Range(0, 200), 10).ToSlice()
+		if !slices.EqualFunc(result, want, slices.Equal[[]int]) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_ZipWithIndexFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+	}{
+		{dataSize: 0},
+		{dataSize: 1},
+		{dataSize: 1000},
+	} {
+		var data []string
+		var want []Indexed[string]
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, strconv.Itoa(i*2))
+			want = append(want, Indexed[string]{
+				Index: int64(i),
+				Value: strconv.Itoa(i * 2),
+			})
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := ZipWithIndex(s).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+
+	t.Run("filtered", func(t *testing.T) {
+		for _, parallel := range [...]bool{false, true} {
+			s := Range(0, 10)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := ZipWithIndex(s.Filter(func(v int) bool {
+				return v%3 == 0
+			})).ToSlice()
+			want := []Indexed[int]{
+				{Index: 0, Value: 0},
+				{Index: 1, Value: 3},
+				{Index: 2, Value: 6},
+				{Index: 3, Value: 9},
+			}
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	})
+}
+
+func TestStream_MapIndexedFunc(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of("a", "b", "c")
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := MapIndexed(s, func(i int64, v string) string {
+			return strconv.FormatInt(i, 10) + ":" + v
+		}).ToSlice()
+		want := []string{"0:a", "1:b", "2:c"}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestStream_FilterIndexedFunc(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(10, 20)
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		// every third element, regardless of its value
+		result := FilterIndexed(s, func(i int64, v int) bool {
+			return i%3 == 0
+		}).ToSlice()
+		want := []int{10, 13, 16, 19}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		naturals := Iterate(0, func(v int) int { return v + 1 })
+		result := FilterIndexed(naturals, func(i int64, v int) bool {
+			return i%2 == 1
+		}).Limit(3).ToSlice()
+		want := []int{1, 3, 5}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
 func TestStream_SortedFunc(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
@@ -90,17 +792,73 @@ func TestStream_SortedFunc(t *testing.T) {
 			want = append(want, i)
 		}
 
-		for _, parallel := range [...]bool{false, true} {
-			s := Of(data...)
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := Sorted(s).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+}
+
+func TestStream_SortedByFunc(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+	people := []person{
+		{name: "Carol", age: 30},
+		{name: "Alice", age: 25},
+		{name: "Bob", age: 30},
+		{name: "Dave", age: 25},
+		{name: "Alice", age: 20},
+	}
+	name := func(p person) string { return p.name }
+	age := func(p person) int { return p.age }
+
+	for _, parallel := range [...]bool{false, true} {
+		t.Run("single key", func(t *testing.T) {
+			s := Of(people...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := SortedBy(s, age).ToSlice()
+			want := []person{
+				{name: "Alice", age: 20},
+				{name: "Alice", age: 25},
+				{name: "Dave", age: 25},
+				{name: "Carol", age: 30},
+				{name: "Bob", age: 30},
+			}
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		})
+
+		t.Run("multiple keys", func(t *testing.T) {
+			s := Of(people...)
 			if parallel {
 				s = s.Parallel()
 			}
 
-			result := Sorted(s).ToSlice()
+			result := SortedBy(s, age, ThenBy(name)).ToSlice()
+			want := []person{
+				{name: "Alice", age: 20},
+				{name: "Alice", age: 25},
+				{name: "Dave", age: 25},
+				{name: "Bob", age: 30},
+				{name: "Carol", age: 30},
+			}
 			if !slices.Equal(result, want) {
 				t.Errorf("result is %v, want %v", result, want)
 			}
-		}
+		})
 	}
 }
 
@@ -282,6 +1040,86 @@ func TestStream_IterateNFunc(t *testing.T) {
 	}
 }
 
+func TestStream_MapFunc_Bind(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[string] {
+			s := Of("a,b", "c", "d,e,f")
+			if parallel {
+				s = s.ParallelN(4)
+			}
+			return s
+		}
+
+		counts := Map(newStream(), function.Bind2(strings.Count, ",")).ToSlice()
+		if want := []int{1, 0, 2}; !slices.Equal(counts, want) {
+			t.Errorf("[%v] counts is %v, want %v", parallel, counts, want)
+		}
+
+		prefixed := Map(newStream(), function.Bind1(func(prefix, s string) string {
+			return prefix + s
+		}, "x:")).ToSlice()
+		if want := []string{"x:a,b", "x:c", "x:d,e,f"}; !slices.Equal(prefixed, want) {
+			t.Errorf("[%v] prefixed is %v, want %v", parallel, prefixed, want)
+		}
+	}
+
+	repeat := function.Curry(strings.Repeat)
+	if r := repeat("ab")(3); r != "ababab" {
+		t.Errorf("repeat(\"ab\")(3) is %q, want %q", r, "ababab")
+	}
+}
+
+func TestStream_MapFunc_Memoize(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		var calls atomic.Int64
+		square := function.MemoizeFunc(func(v int) int {
+			calls.Add(1)
+			return v * v
+		})
+
+		s := Map(Range(0, 100), func(v int) int { return v % 10 })
+		if parallel {
+			s = s.ParallelN(4)
+		}
+
+		result := Sum(Map(s, square))
+		if result != 2850 {
+			t.Errorf("[%v] result is %d, want 2850", parallel, result)
+		}
+		if !parallel && calls.Load() != 10 {
+			// parallel calls may compute a result more than once.
+			t.Errorf("calls is %d, want 10", calls.Load())
+		}
+	}
+
+	var calls int
+	twice := function.MemoizeFuncN(func(v int) int {
+		calls++
+		return v * 2
+	}, 2)
+	for _, v := range []int{1, 2, 1, 3, 1, 2} {
+		if r := twice(v); r != v*2 {
+			t.Errorf("twice(%d) is %d, want %d", v, r, v*2)
+		}
+	}
+	// 1, 2 and 3 are computed, and then 2 is computed again because it is
+	// evicted by 3 as the least recently used.
+	if calls != 4 {
+		t.Errorf("calls is %d, want 4", calls)
+	}
+
+	var supplied int
+	supplier := function.Memoize(func() int {
+		supplied++
+		return 42
+	})
+	result := Generate(supplier).Limit(5).ToSlice()
+	if !slices.Equal(result, []int{42, 42, 42, 42, 42}) || supplied != 1 {
+		t.Errorf("result is %v and supplied is %d, want five 42s and 1",
+			result, supplied)
+	}
+}
+
 func TestStream_GenerateFunc(t *testing.T) {
 	t.Run("constant", func(t *testing.T) {
 		Generate[int](func() int {
@@ -351,6 +1189,116 @@ func TestStream_ConcatFunc(t *testing.T) {
 	}
 }
 
+func TestStream_ConcatFunc_Variadic(t *testing.T) {
+	t.Run("no stream", func(t *testing.T) {
+		result := Concat[int]().ToSlice()
+		if len(result) != 0 {
+			t.Errorf("result is %v, want []", result)
+		}
+	})
+
+	t.Run("many streams", func(t *testing.T) {
+		for _, parallel := range [...]bool{false, true} {
+			var streams []Stream[int]
+			var want []int
+			for i := 0; i < 10; i++ {
+				var data []int
+				for j := 0; j < i*10; j++ {
+					data = append(data, len(want))
+					want = append(want, len(want))
+				}
+				s := Of(data...)
+				if parallel {
+					s = s.Parallel()
+				}
+				streams = append(streams, s)
+			}
+
+			result := Concat(streams...).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		result := Concat(Of(1, 2), Of(3, 4), Of(5, 6)).Limit(3).ToSlice()
+		if want := []int{1, 2, 3}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel infinite", func(t *testing.T) {
+		s := Concat(Of(1, 2), Iterate(10, func(v int) int {
+			return v + 1
+		})).ParallelN(4)
+		result := s.Skip(1).Limit(4).ToSlice()
+		if want := []int{2, 10, 11, 12}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("reordered", func(t *testing.T) {
+		// the concatenated stream is sequential, so ForEach follows its order.
+		var result []int
+		Concat(jittered(100), Range(100, 200)).ForEach(func(v int) {
+			result = append(result, v)
+		})
+		if want := Range(0, 200).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_MergeSortedFunc(t *testing.T) {
+	for _, tc := range [...]struct {
+		a, b []int
+		want []int
+	}{
+		{a: nil, b: nil, want: nil},
+		{a: []int{1, 2}, b: nil, want: []int{1, 2}},
+		{a: nil, b: []int{1, 2}, want: []int{1, 2}},
+		{a: []int{1, 3, 5, 7}, b: []int{2, 4, 6}, want: []int{1, 2, 3, 4, 5, 6, 7}},
+		{a: []int{1, 1, 9}, b: []int{0, 1, 10, 11}, want: []int{0, 1, 1, 1, 9, 10, 11}},
+	} {
+		result := MergeSorted(Of(tc.a...), Of(tc.b...), cmp.Compare[int]).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("MergeSorted(%v, %v) is %v, want %v",
+				tc.a, tc.b, result, tc.want)
+		}
+	}
+
+	t.Run("stable", func(t *testing.T) {
+		type entry struct {
+			key    int
+			source string
+		}
+		byKey := func(a, b entry) int {
+			return cmp.Compare(a.key, b.key)
+		}
+
+		result := MergeSorted(
+			Of(entry{1, "a"}, entry{2, "a"}),
+			Of(entry{1, "b"}, entry{2, "b"}),
+			byKey).ToSlice()
+		want := []entry{{1, "a"}, {1, "b"}, {2, "a"}, {2, "b"}}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("infinite", func(t *testing.T) {
+		evens := Iterate(0, func(v int) int { return v + 2 })
+		odds := Iterate(1, func(v int) int { return v + 2 })
+
+		result := MergeSorted(evens, odds, cmp.Compare[int]).Limit(10).ToSlice()
+		want := Range(0, 10).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
 func TestStream_FlatMapFunc(t *testing.T) {
 	mapToRuneStream := func(s string) Stream[rune] {
 		runes := []rune(s)
@@ -371,6 +1319,131 @@ func TestStream_FlatMapFunc(t *testing.T) {
 	}
 }
 
+func TestStream_FlatMapSliceFunc(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of("abc", "", "d", "efgh", "", "ijklmn")
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := FlatMapSlice(s, func(s string) []rune {
+			return []rune(s)
+		}).ToSlice()
+
+		want := "abcdefghijklmn"
+		if string(result) != want {
+			t.Errorf("[%v] string(result) is %q, want %q", parallel, string(result), want)
+		}
+	}
+
+	t.Run("parallel", func(t *testing.T) {
+		// each element v is expanded to v%3 copies of v.
+		expand := func(v int) []int {
+			var vs []int
+			for i := 0; i < v%3; i++ {
+				vs = append(vs, v)
+			}
+			return vs
+		}
+		result := FlatMapSlice(jittered(200), expand).ToSlice()
+
+		var want []int
+		for v := 0; v < 200; v++ {
+			want = append(want, expand(v)...)
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		result := FlatMapSlice(Of(1, 2, 3), func(v int) []int {
+			return nil
+		}).ToSlice()
+		if len(result) != 0 {
+			t.Errorf("result is %v, want []", result)
+		}
+	})
+}
+
+func TestStream_FlattenFunc(t *testing.T) {
+	result := Flatten(Of(
+		Of(1, 2, 3),
+		Empty[int](),
+		Of(4),
+		Range(5, 10).ParallelN(4),
+	)).ToSlice()
+	want := Range(1, 10).ToSlice()
+	if !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		result := Flatten(Empty[Stream[int]]()).ToSlice()
+		if len(result) != 0 {
+			t.Errorf("result is %v, want []", result)
+		}
+	})
+
+	t.Run("infinite", func(t *testing.T) {
+		pages := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}), func(page int) Stream[int] {
+			return Range(page*10, page*10+10)
+		})
+
+		result := Flatten(pages).Limit(25).ToSlice()
+		want := Range(0, 25).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel inner", func(t *testing.T) {
+		// the first element is delayed, so that it arrives last.
+		inner := Range(0, 10).ParallelN(4).Peek(func(v int) {
+			if v == 0 {
+				time.Sleep(20 * time.Millisecond)
+			}
+		})
+		result := Flatten(Of(inner, Range(10, 20))).ToSlice()
+		want := Range(0, 20).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		result := Flatten(Map(jittered(20), func(page int) Stream[int] {
+			return Range(page*10, page*10+10)
+		})).ToSlice()
+		want := Range(0, 200).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_FlattenOptionalFunc(t *testing.T) {
+	data := []*Optional[int]{
+		OptionalOf(1), OptionalEmpty[int](), OptionalOf(2), OptionalEmpty[int](),
+		OptionalOf(3),
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(data...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := FlattenOptional(s).ToSlice()
+		want := []int{1, 2, 3}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
 func TestStream_RangeFunc(t *testing.T) {
 	rangeValues := Range(0, 100).ToSlice()
 
@@ -397,6 +1470,46 @@ func TestStream_RangeClosedFunc(t *testing.T) {
 	}
 }
 
+func TestStream_RangeByFunc(t *testing.T) {
+	t.Run("int", func(t *testing.T) {
+		for _, tc := range [...]struct {
+			start, end, step int
+			want             []int
+		}{
+			{start: 0, end: 10, step: 3, want: []int{0, 3, 6, 9}},
+			{start: 0, end: 9, step: 3, want: []int{0, 3, 6}},
+			{start: 10, end: 0, step: -4, want: []int{10, 6, 2}},
+			{start: 10, end: 0, step: 1, want: nil},
+			{start: 0, end: 0, step: 1, want: nil},
+		} {
+			result := RangeBy(tc.start, tc.end, tc.step).ToSlice()
+			if !slices.Equal(result, tc.want) {
+				t.Errorf("RangeBy(%d, %d, %d) is %v, want %v",
+					tc.start, tc.end, tc.step, result, tc.want)
+			}
+		}
+	})
+
+	t.Run("uint", func(t *testing.T) {
+		result := RangeBy[uint](1, 0, 1).ToSlice()
+		if len(result) != 0 {
+			t.Errorf("result is %v, want []", result)
+		}
+	})
+
+	t.Run("float64", func(t *testing.T) {
+		result := RangeBy(0.0, 1.0, 0.1).ToSlice()
+		if len(result) != 10 {
+			t.Fatalf("len(result) is %d, want 10", len(result))
+		}
+		for i, v := range result {
+			if want := float64(i) * 0.1; v != want {
+				t.Errorf("result[%d] is %v, want %v", i, v, want)
+			}
+		}
+	})
+}
+
 func TestStream_MaxFunc(t *testing.T) {
 	rand.Seed(time.Now().Unix())
 
@@ -490,3 +1603,212 @@ func TestStream_MinFunc(t *testing.T) {
 		}
 	}
 }
+
+func TestStream_MinMaxSortedFunc_Ordered(t *testing.T) {
+	words := []string{"pear", "apple", "fig", "banana"}
+
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[string] {
+			s := Of(words...)
+			if parallel {
+				s = s.Parallel()
+			}
+			return s
+		}
+
+		if min := Min(newStream()).Get(); min != "apple" {
+			t.Errorf("[%v] min is %q, want %q", parallel, min, "apple")
+		}
+		if max := Max(newStream()).Get(); max != "pear" {
+			t.Errorf("[%v] max is %q, want %q", parallel, max, "pear")
+		}
+
+		result := Sorted(newStream()).ToSlice()
+		want := []string{"apple", "banana", "fig", "pear"}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	nan := math.NaN()
+	result := Sorted(Of(2.0, nan, 1.0, nan, 0.5)).ToSlice()
+	if !math.IsNaN(result[0]) || !math.IsNaN(result[1]) ||
+		!slices.Equal(result[2:], []float64{0.5, 1.0, 2.0}) {
+		t.Errorf("result is %v, want [NaN NaN 0.5 1 2]", result)
+	}
+}
+
+func TestStream_ToMapFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := ToMap(s, strconv.Itoa, func(v int) int {
+			return v * v
+		})
+		if len(result) != 100 {
+			t.Errorf("[%v] len(result) is %d, want 100", parallel, len(result))
+		}
+		for i := 0; i < 100; i++ {
+			if v := result[strconv.Itoa(i)]; v != i*i {
+				t.Errorf("[%v] result[%q] is %d, want %d", parallel, i, v, i*i)
+			}
+		}
+	}
+}
+
+func TestStream_ToSetFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Map(Range(0, 100), func(v int) int {
+			return v % 10
+		})
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := ToSet(s)
+		if len(result) != 10 {
+			t.Errorf("[%v] len(result) is %d, want 10", parallel, len(result))
+		}
+		for i := 0; i < 10; i++ {
+			if _, ok := result[i]; !ok {
+				t.Errorf("[%v] result doesn't contain %d", parallel, i)
+			}
+		}
+	}
+}
+
+func TestStream_AverageFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(1, 101)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		average := Average(s)
+		if !average.IsPresent() || average.Get() != 50.5 {
+			t.Errorf("[%v] average is %v, want 50.5", parallel, average)
+		}
+
+		empty := Empty[float64]()
+		if parallel {
+			empty = empty.Parallel()
+		}
+		if average := Average(empty); average.IsPresent() {
+			t.Errorf("[%v] average is %v, want empty", parallel, average)
+		}
+	}
+}
+
+func TestStream_SumFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(1, 101)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		if sum := Sum(s); sum != 5050 {
+			t.Errorf("[%v] sum is %d, want 5050", parallel, sum)
+		}
+	}
+
+	t.Run("compensated", func(t *testing.T) {
+		// each of the tiny values is lost by naive summation.
+		data := []float64{1.0}
+		for i := 0; i < 100000; i++ {
+			data = append(data, 1e-17)
+		}
+		want := 1.0 + 1e-12
+
+		for _, parallel := range []bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			sum := Sum(s)
+			if math.Abs(sum-want) > 1e-15 {
+				t.Errorf("[%v] sum is %.17g, want %.17g", parallel, sum, want)
+			}
+		}
+	})
+
+	t.Run("named float type", func(t *testing.T) {
+		type celsius float64
+
+		sum := Sum(Of[celsius](1.5, 2.25, -0.75))
+		if sum != 3.0 {
+			t.Errorf("sum is %v, want 3.0", sum)
+		}
+	})
+
+	t.Run("infinity", func(t *testing.T) {
+		sum := Sum(Of(math.Inf(1), math.Inf(1), 1.0))
+		if !math.IsInf(sum, 1) {
+			t.Errorf("sum is %v, want +Inf", sum)
+		}
+	})
+}
+
+func TestStream_SummarizeFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(1, 101)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		stats := Summarize(s)
+		if stats.GetCount() != 100 {
+			t.Errorf("[%v] count is %d, want 100", parallel, stats.GetCount())
+		}
+		if stats.GetSum() != 5050 {
+			t.Errorf("[%v] sum is %d, want 5050", parallel, stats.GetSum())
+		}
+		if stats.GetMin() != 1 {
+			t.Errorf("[%v] min is %d, want 1", parallel, stats.GetMin())
+		}
+		if stats.GetMax() != 100 {
+			t.Errorf("[%v] max is %d, want 100", parallel, stats.GetMax())
+		}
+		if stats.GetAverage() != 50.5 {
+			t.Errorf("[%v] average is %v, want 50.5", parallel, stats.GetAverage())
+		}
+	}
+
+	stats := Summarize(Empty[int]())
+	if stats.GetCount() != 0 || stats.GetAverage() != 0 {
+		t.Errorf("stats of empty stream is %v", stats)
+	}
+}
+
+func TestStream_GroupByFunc(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		groups := GroupBy(s, func(v int) int {
+			return v % 3
+		})
+		if len(groups) != 3 {
+			t.Errorf("[%v] len(groups) is %d, want 3", parallel, len(groups))
+		}
+		for key, group := range groups {
+			var want []int
+			for i := key; i < 100; i += 3 {
+				want = append(want, i)
+			}
+			if !slices.Equal(group, want) {
+				t.Errorf("[%v] groups[%d] is %v, want %v",
+					parallel, key, group, want)
+			}
+		}
+	}
+
+	if groups := GroupBy(Empty[int](), strconv.Itoa); len(groups) != 0 {
+		t.Errorf("groups is %v, want empty", groups)
+	}
+}
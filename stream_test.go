@@ -1,17 +1,269 @@
-// Copyright © 2020 Yoshiki Shibata. All rights reserved.
+// Copyright © 2020, 2026 Yoshiki Shibata. All rights reserved.
 
 package gostream
 
 import (
 	"cmp"
+	"errors"
+	"fmt"
 	"math/rand"
+	"runtime"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/YoshikiShibata/gostream/function"
 	"golang.org/x/exp/slices"
 )
 
+func TestStream_Sequential_PullBased(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	var maxGoroutines int
+	observe := func() {
+		maxGoroutines = max(maxGoroutines, runtime.NumGoroutine())
+	}
+
+	result := Map(Of(5, 1, 4, 2, 3).Filter(func(v int) bool {
+		observe()
+		return v != 4
+	}).Skip(1).Limit(3).Peek(func(int) {
+		observe()
+	}), func(v int) string {
+		observe()
+		return fmt.Sprint(v * 10)
+	}).ToSlice()
+
+	want := []string{"10", "20", "30"}
+	if !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+	if maxGoroutines > baseline {
+		t.Errorf("%d goroutines are started", maxGoroutines-baseline)
+	}
+
+	t.Run("reuse", func(t *testing.T) {
+		s := Of(1, 2, 3)
+		_ = s.Filter(func(int) bool { return true })
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("reusing a stream doesn't panic")
+			}
+		}()
+		s.ToSlice()
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		// Sorted is not fused, so the pipeline is served through channels.
+		s := Of(3, 1, 2).Filter(func(v int) bool { return v != 2 })
+		result := Map(s.Sorted(cmp.Compare[int]), func(v int) int {
+			return v * 2
+		}).Limit(5).ToSlice()
+		if want := []int{2, 6}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_FusedStages(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		// deep returns the elements of a channel-based stream through depth
+		// stages of Filter, Map and Peek, and the maximum number of
+		// goroutines observed in the last stage.
+		deep := func(depth int) ([]int, int) {
+			// Iterate and Limit are not fused, so the source is channel-based.
+			s := Iterate(0, func(v int) int { return v + 1 }).Limit(100)
+			if parallel {
+				s = s.ParallelN(4)
+			}
+			for i := 0; i < depth; i++ {
+				i := i
+				switch i % 3 {
+				case 0:
+					s = s.Filter(func(v int) bool { return v%7 != i })
+				case 1:
+					s = Map(s, func(v int) int { return v + 1 })
+				case 2:
+					s = s.Peek(func(int) {})
+				}
+			}
+
+			var maxGoroutines int
+			var lock sync.Mutex
+			result := s.Peek(func(int) {
+				lock.Lock()
+				defer lock.Unlock()
+				maxGoroutines = max(maxGoroutines, runtime.NumGoroutine())
+			}).ToSlice()
+			return result, maxGoroutines
+		}
+
+		_, shallow := deep(1)
+		result, deeper := deep(30)
+		// without fusion, each stage would start its own goroutines; a few
+		// goroutines of the previous pipeline may not have exited yet.
+		if deeper-shallow >= 10 {
+			t.Errorf("[%v] %d goroutines for 30 stages, but %d for 1 stage",
+				parallel, deeper, shallow)
+		}
+
+		var want []int
+		for v := 0; v < 100; v++ {
+			w, ok := v, true
+			for i := 0; i < 30 && ok; i++ {
+				switch i % 3 {
+				case 0:
+					ok = w%7 != i
+				case 1:
+					w++
+				}
+			}
+			if ok {
+				want = append(want, w)
+			}
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	t.Run("early termination", func(t *testing.T) {
+		for _, parallel := range [...]bool{false, true} {
+			s := Generate(func() int { return 1 })
+			if parallel {
+				s = s.ParallelN(4)
+			}
+			s = Map(s.Filter(func(v int) bool { return v > 0 }),
+				func(v int) int { return v * 2 })
+			if parallel {
+				// Limit of an ordered parallel stream examines all elements.
+				s = s.Unordered()
+			}
+			result := s.Limit(3).ToSlice()
+			if want := []int{2, 2, 2}; !slices.Equal(result, want) {
+				t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+			}
+		}
+	})
+}
+
+func TestStream_ShortCircuit_Cancel(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[int] {
+			s := Iterate(0, func(v int) int { return v + 1 })
+			if parallel {
+				s = s.ParallelN(4)
+			}
+			return Map(s.Filter(func(v int) bool { return v%2 == 0 }),
+				func(v int) int { return v * 10 })
+		}
+
+		for name, terminal := range map[string]func(){
+			"AnyMatch": func() {
+				newStream().AnyMatch(func(v int) bool { return v > 100 })
+			},
+			"AllMatch": func() {
+				newStream().AllMatch(func(v int) bool { return v < 100 })
+			},
+			"NoneMatch": func() {
+				newStream().NoneMatch(func(v int) bool { return v > 100 })
+			},
+			"FindAny": func() {
+				newStream().FindAny()
+			},
+			"FindFirst": func() {
+				newStream().FindFirst()
+			},
+			"Limit": func() {
+				newStream().Unordered().Limit(3).ToSlice()
+			},
+			"FlatMap": func() {
+				FlatMap(newStream(), func(v int) Stream[int] {
+					return Iterate(v, func(w int) int { return w + 1 })
+				}).AnyMatch(func(v int) bool { return v > 100 })
+			},
+		} {
+			baseline := runtime.NumGoroutine()
+			terminal()
+
+			// all the goroutines of the pipeline terminate promptly.
+			left := runtime.NumGoroutine() - baseline
+			for deadline := time.Now().Add(time.Second); left > 0 &&
+				time.Now().Before(deadline); {
+				time.Sleep(time.Millisecond)
+				left = runtime.NumGoroutine() - baseline
+			}
+			if left > 0 {
+				t.Errorf("[%v] %d goroutines are left after %s",
+					parallel, left, name)
+			}
+		}
+	}
+}
+
+func TestStream_Parallel_Split(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 1000} {
+		for _, n := range []int{1, 3, 8} {
+			newStream := func() Stream[int] {
+				return Map(Range(0, size).ParallelN(n).Filter(func(v int) bool {
+					return v%3 != 0
+				}), func(v int) int {
+					return v * 2
+				})
+			}
+
+			var want []int
+			for v := 0; v < size; v++ {
+				if v%3 != 0 {
+					want = append(want, v*2)
+				}
+			}
+
+			if result := newStream().ToSlice(); !slices.Equal(result, want) {
+				t.Errorf("[%d, %d] ToSlice is %v, want %v", size, n, result, want)
+			}
+
+			if count := newStream().Count(); count != len(want) {
+				t.Errorf("[%d, %d] Count is %d, want %d", size, n, count, len(want))
+			}
+
+			var sum atomic.Int64
+			newStream().ForEach(func(v int) { sum.Add(int64(v)) })
+			wantSum := 0
+			for _, v := range want {
+				wantSum += v
+			}
+			if sum.Load() != int64(wantSum) {
+				t.Errorf("[%d, %d] sum is %d, want %d", size, n, sum.Load(), wantSum)
+			}
+
+			first := newStream().FindFirst()
+			if len(want) == 0 {
+				if first.IsPresent() {
+					t.Errorf("[%d, %d] FindFirst is %v, want empty", size, n, first)
+				}
+			} else if first.Get() != want[0] {
+				t.Errorf("[%d, %d] FindFirst is %v, want %d", size, n, first, want[0])
+			}
+
+			// Skip and Limit pull the parts in encounter order.
+			result := newStream().Skip(2).Limit(3).ToSlice()
+			wantSkipped := want[min(2, len(want)):min(5, len(want))]
+			if !slices.Equal(result, wantSkipped) {
+				t.Errorf("[%d, %d] Skip and Limit is %v, want %v",
+					size, n, result, wantSkipped)
+			}
+
+			// Sum is not fused, so the parts are served through channels.
+			if sum := Sum(newStream()); sum != wantSum {
+				t.Errorf("[%d, %d] Sum is %d, want %d", size, n, sum, wantSum)
+			}
+		}
+	}
+}
+
 func TestStream_ForEach(t *testing.T) {
 	data := make([]int, 1000)
 	for i := 0; i < len(data); i++ {
@@ -47,20 +299,184 @@ func TestStream_ForEach(t *testing.T) {
 				len(result), len(data))
 		}
 
-		isSorted := func() bool {
-			for i := 1; i < len(result); i++ {
-				if result[i-1] > result[i] {
-					return false
-				}
+		// The parts of the elements are processed in parallel, but each
+		// part is contiguous, so the result may happen to be sorted if the
+		// parts are processed in turn.
+		slices.Sort(result)
+		if !slices.Equal(result, data) {
+			t.Errorf("sorted result is %v, want %v", result, data)
+		}
+	})
+}
+
+func TestStream_ForEachOrdered_ConsumerCombinators(t *testing.T) {
+	var sum int
+	var evens []int
+	var failed []int
+	var errs []error
+
+	add := function.Consumer[int](func(v int) { sum += v })
+	collectEven := function.ConsumerErr(func(v int) error {
+		if v%2 != 0 {
+			return fmt.Errorf("%d is odd", v)
+		}
+		evens = append(evens, v)
+		return nil
+	}, func(v int, err error) {
+		failed = append(failed, v)
+		errs = append(errs, err)
+	})
+
+	Range(0, 6).ForEachOrdered(add.AndThen(collectEven))
+
+	if sum != 15 {
+		t.Errorf("sum is %d, want 15", sum)
+	}
+	if want := []int{0, 2, 4}; !slices.Equal(evens, want) {
+		t.Errorf("evens is %v, want %v", evens, want)
+	}
+	if want := []int{1, 3, 5}; !slices.Equal(failed, want) {
+		t.Errorf("failed is %v, want %v", failed, want)
+	}
+	if len(errs) != 3 || errs[0].Error() != "1 is odd" {
+		t.Errorf("errs is %v, want 3 errors", errs)
+	}
+
+	// a nil error handler discards errors.
+	Range(0, 3).ForEachOrdered(function.ConsumerErr(func(v int) error {
+		return errors.New("discarded")
+	}, nil))
+}
+
+func TestStream_ForEachOrdered(t *testing.T) {
+	data := make([]int, 1000)
+	for i := 0; i < len(data); i++ {
+		data[i] = i
+	}
+
+	// the first elements are delayed, so that they arrive last.
+	delay := func(v int) {
+		if v < 5 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	for _, parallel := range []bool{false, true} {
+		s := Of(data...)
+		if parallel {
+			s = s.ParallelN(8)
+		}
+
+		var result []int
+		s.Peek(delay).ForEachOrdered(func(v int) {
+			result = append(result, v)
+		})
+		if !slices.Equal(result, data) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, data)
+		}
+	}
+
+	t.Run("filtered", func(t *testing.T) {
+		var result []int
+		Of(data...).ParallelN(8).Peek(delay).Filter(func(v int) bool {
+			return v%10 == 0
+		}).ForEachOrdered(func(v int) {
+			result = append(result, v)
+		})
+
+		want := []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}
+		if !slices.Equal(result[:10], want) {
+			t.Errorf("result[:10] is %v, want %v", result[:10], want)
+		}
+		if len(result) != 100 {
+			t.Errorf("len(result) is %d, want 100", len(result))
+		}
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		var result []int
+		Of(data...).ParallelN(8).Unordered().ForEachOrdered(func(v int) {
+			result = append(result, v)
+		})
+
+		sort.Ints(result)
+		if !slices.Equal(result, data) {
+			t.Errorf("sorted result is %v, want %v", result, data)
+		}
+	})
+}
+
+func TestStream_TryForEach(t *testing.T) {
+	errTooLarge := errors.New("too large")
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		var count atomic.Int64
+		err := s.TryForEach(func(v int) error {
+			count.Add(1)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("[%v] err is %v, want nil", parallel, err)
+		}
+		if count.Load() != 100 {
+			t.Errorf("[%v] count is %d, want 100", parallel, count.Load())
+		}
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Iterate(0, func(v int) int {
+			return v + 1
+		})
+		if parallel {
+			s = s.Parallel()
+		}
+
+		err := s.TryForEach(func(v int) error {
+			if v >= 10 {
+				return errTooLarge
 			}
-			return true
+			return nil
+		})
+		if err != errTooLarge {
+			t.Errorf("[%v] err is %v, want %v", parallel, err, errTooLarge)
 		}
-		if goMaxProcs != 1 && isSorted() {
-			t.Errorf("result(%v) is sorted\n", result)
+	}
+
+	t.Run("timed out", func(t *testing.T) {
+		err := Generate(func() int {
+			time.Sleep(10 * time.Millisecond)
+			return 1
+		}).WithTimeout(50 * time.Millisecond).TryForEach(func(v int) error {
+			return nil
+		})
+		if err != ErrTimeout {
+			t.Errorf("err is %v, want %v", err, ErrTimeout)
 		}
 	})
 }
 
+func TestStream_Drain(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 1000)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		var count atomic.Int64
+		s.Peek(func(v int) {
+			count.Add(1)
+		}).Drain()
+		if count.Load() != 1000 {
+			t.Errorf("[%v] count is %d, want 1000", parallel, count.Load())
+		}
+	}
+}
+
 func TestStream_Filter(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
@@ -96,6 +512,106 @@ func TestStream_Filter(t *testing.T) {
 	}
 }
 
+func TestStream_Filter_Predicates(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		newStream := func() Stream[int] {
+			s := Range(0, 10)
+			if parallel {
+				s = s.Parallel()
+			}
+			return s
+		}
+
+		result := newStream().Filter(function.Between(3, 6)).ToSlice()
+		want := []int{3, 4, 5, 6}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] Between: result is %v, want %v", parallel, result, want)
+		}
+
+		result = newStream().Filter(function.In(8, 1, 5, 42)).ToSlice()
+		want = []int{1, 5, 8}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] In: result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	one, two := 1, 2
+	result := Of(nil, &one, nil, &two).Filter(function.NotNil[int]()).ToSlice()
+	want := []*int{&one, &two}
+	if !slices.Equal(result, want) {
+		t.Errorf("NotNil: result is %v, want %v", result, want)
+	}
+
+	if function.In[int]()(0) {
+		t.Errorf("In() matches 0")
+	}
+}
+
+func TestStream_Filter_PredicateCombinators(t *testing.T) {
+	even := function.Predicate[int](func(v int) bool { return v%2 == 0 })
+	small := function.Predicate[int](func(v int) bool { return v < 5 })
+
+	for _, tc := range [...]struct {
+		name      string
+		predicate function.Predicate[int]
+		want      []int
+	}{
+		{"And", function.And(even, small), []int{0, 2, 4}},
+		{"Or", function.Or(even, small), []int{0, 1, 2, 3, 4, 6, 8}},
+		{"Not", function.Not(even), []int{1, 3, 5, 7, 9}},
+		{"IsEqual", function.IsEqual(7), []int{7}},
+		{"AndMethod", even.And(small.Negate()), []int{6, 8}},
+		{"OrMethod", function.IsEqual(9).Or(function.IsEqual(1)), []int{1, 9}},
+		{"AndNone", function.And[int](), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"OrNone", function.Or[int](), nil},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Range(0, 10)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.Filter(tc.predicate).ToSlice()
+			if !slices.Equal(result, tc.want) {
+				t.Errorf("[%s, %v] result is %v, want %v",
+					tc.name, parallel, result, tc.want)
+			}
+		}
+	}
+}
+
+func TestStream_Sample(t *testing.T) {
+	data := make([]int, 10000)
+	for i := 0; i < len(data); i++ {
+		data[i] = i
+	}
+
+	for _, tc := range [...]struct {
+		p        float64
+		min, max int
+	}{
+		{p: 0, min: 0, max: 0},
+		{p: 0.5, min: 4500, max: 5500},
+		{p: 1, min: 10000, max: 10000},
+	} {
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.Sample(tc.p, rand.New(rand.NewSource(1))).ToSlice()
+			if len(result) < tc.min || len(result) > tc.max {
+				t.Errorf("p = %v: len(result) is %d, want [%d, %d]",
+					tc.p, len(result), tc.min, tc.max)
+			}
+			if !slices.IsSorted(result) {
+				t.Errorf("p = %v: result is not in encounter order", tc.p)
+			}
+		}
+	}
+}
+
 func TestStream_Sorted(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
@@ -118,162 +634,1131 @@ func TestStream_Sorted(t *testing.T) {
 				s = s.Parallel()
 			}
 
-			result := s.Sorted(func(t1, t2 int) int {
-				return cmp.Compare(t1, t2)
-			}).ToSlice()
+			result := s.Sorted(func(t1, t2 int) int {
+				return cmp.Compare(t1, t2)
+			}).ToSlice()
+
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+}
+
+func TestStream_Sorted_Comparator(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+	people := []person{
+		{name: "Bob", age: 30}, {name: "Alice", age: 25},
+		{name: "Carol", age: 30}, {name: "Dave", age: 25},
+	}
+
+	byAge := function.Comparator[person](func(p1, p2 person) int {
+		return cmp.Compare(p1.age, p2.age)
+	})
+	byName := function.FromLess(func(p1, p2 person) bool {
+		return p1.name < p2.name
+	})
+	byAgeDescThenName := byAge.Reversed().ThenComparing(byName)
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(people...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := s.Sorted(byAgeDescThenName).ToSlice()
+		want := []person{
+			{name: "Bob", age: 30}, {name: "Carol", age: 30},
+			{name: "Alice", age: 25}, {name: "Dave", age: 25},
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	var less Less[int] = function.NaturalOrder[int]().Less()
+	if !less(1, 2) || less(2, 1) || less(1, 1) {
+		t.Errorf("less is not consistent with natural order")
+	}
+
+	c := function.FromLess(less)
+	if c(1, 2) >= 0 || c(2, 1) <= 0 || c(1, 1) != 0 {
+		t.Errorf("FromLess(less) is not consistent with natural order")
+	}
+}
+
+func TestStream_Sorted_ComparingBy(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+	people := []person{
+		{name: "Bob", age: 30}, {name: "Alice", age: 25},
+		{name: "Carol", age: 35}, {name: "Dave", age: 20},
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(people...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := s.Sorted(function.ComparingBy(func(p person) string {
+			return p.name
+		}).Reversed()).ToSlice()
+		want := []person{
+			{name: "Dave", age: 20}, {name: "Carol", age: 35},
+			{name: "Bob", age: 30}, {name: "Alice", age: 25},
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+
+		s = Of(people...)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result = s.Sorted(function.ComparingByLess(
+			func(p person) int { return p.age },
+			func(a, b int) bool { return a > b },
+		)).ToSlice()
+		want = []person{
+			{name: "Carol", age: 35}, {name: "Bob", age: 30},
+			{name: "Alice", age: 25}, {name: "Dave", age: 20},
+		}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestStream_MinMax_NilFirstLast(t *testing.T) {
+	one, two, three := 1, 2, 3
+	pointers := []*int{&two, nil, &one, &three, nil}
+
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(pointers...)
+		if parallel {
+			s = s.Parallel()
+		}
+		min := s.Min(Less[*int](function.NilLast(function.NaturalOrder[int]()).Less()))
+		if min.Get() != &one {
+			t.Errorf("[%v] min is %v, want %v", parallel, min.Get(), &one)
+		}
+
+		s = Of(pointers...)
+		if parallel {
+			s = s.Parallel()
+		}
+		max := s.Max(Less[*int](function.NilFirst(function.NaturalOrder[int]()).Less()))
+		if max.Get() != &three {
+			t.Errorf("[%v] max is %v, want %v", parallel, max.Get(), &three)
+		}
+
+		s = Of(pointers...)
+		if parallel {
+			s = s.Parallel()
+		}
+		result := s.Sorted(function.NilFirst(function.NaturalOrder[int]())).ToSlice()
+		want := []*int{nil, nil, &one, &two, &three}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+}
+
+func TestStream_Reverse(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+	}{
+		{dataSize: 0},
+		{dataSize: 1},
+		{dataSize: 1000},
+	} {
+		var data []int
+		var want []int
+
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, i)
+			want = append(want, tc.dataSize-i-1)
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.Reverse().ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+}
+
+func TestStream_Shuffle(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+	}{
+		{dataSize: 0},
+		{dataSize: 1},
+		{dataSize: 1000},
+	} {
+		var data []int
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, i)
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			shuffled := s.Shuffle(rand.New(rand.NewSource(1)))
+			if unordered := shuffled.(*genericStream[int]).unordered; unordered != parallel {
+				t.Errorf("[%v] unordered is %v, want %v", parallel, unordered, parallel)
+			}
+
+			result := shuffled.ToSlice()
+			if len(result) != len(data) {
+				t.Fatalf("len(result) is %d, want %d", len(result), len(data))
+			}
+			if tc.dataSize > 1 && slices.Equal(result, data) {
+				t.Errorf("result is not shuffled")
+			}
+
+			// the elements of a parallel result may be in any order.
+			again := Of(data...).Shuffle(rand.New(rand.NewSource(1))).ToSlice()
+			if !parallel && !slices.Equal(result, again) {
+				t.Errorf("same seed produces different permutations")
+			}
+
+			slices.Sort(result)
+			if !slices.Equal(result, data) {
+				t.Errorf("sorted result is %v, want %v", result, data)
+			}
+		}
+	}
+}
+
+func TestStream_Prepend(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(3, 4, 5)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := s.Prepend(1, 2).ToSlice()
+		if want := []int{1, 2, 3, 4, 5}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	}
+
+	result := Empty[int]().Prepend().ToSlice()
+	if len(result) != 0 {
+		t.Errorf("result is %v, want []", result)
+	}
+}
+
+func TestStream_Append(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Of(1, 2, 3)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := s.Append(4, 5).ToSlice()
+		if want := []int{1, 2, 3, 4, 5}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	}
+
+	result := Empty[int]().Append(1).ToSlice()
+	if want := []int{1}; !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+}
+
+func TestStream_Peek(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+	}{
+		{dataSize: 0},
+		{dataSize: 1},
+		{dataSize: 1000},
+	} {
+		var data []int
+		var want []int
+
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, tc.dataSize-1-i)
+			want = append(want, i)
+		}
+
+		t.Run("serial", func(t *testing.T) {
+			s := Of(data...)
+
+			peekedChan := make(chan int, tc.dataSize)
+			peekFunc := func(t int) {
+				peekedChan <- t
+			}
+
+			result := s.Peek(peekFunc).Sorted(func(t1, t2 int) int {
+				return cmp.Compare(t1, t2)
+			}).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+
+			close(peekedChan)
+			var peeked []int
+			for v := range peekedChan {
+				peeked = append(peeked, v)
+			}
+
+			if !slices.Equal(peeked, data) {
+				t.Errorf("peeked is %v, want %v", peeked, data)
+			}
+		})
+
+		t.Run("parallel", func(t *testing.T) {
+			s := Of(data...).Parallel()
+
+			peekedChan := make(chan int, tc.dataSize)
+			peekFunc := func(t int) {
+				peekedChan <- t
+			}
+
+			result := s.Peek(peekFunc).Sorted(func(t1, t2 int) int {
+				return cmp.Compare(t1, t2)
+			}).ToSlice()
+
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+
+			close(peekedChan)
+			var peeked []int
+			for v := range peekedChan {
+				peeked = append(peeked, v)
+			}
+
+			if tc.dataSize != 0 && tc.dataSize != 1 && goMaxProcs != 1 {
+				if slices.Equal(peeked, data) {
+					t.Logf("tc.dataSize = %d", tc.dataSize)
+					t.Errorf("peeked is identical to data")
+				}
+			}
+
+			sort.Slice(peeked, func(i, j int) bool {
+				return peeked[i] < peeked[j]
+			})
+			if !slices.Equal(peeked, want) {
+				t.Errorf("peeked is %v, want %v", peeked, want)
+			}
+		})
+	}
+}
+
+func TestStream_PeekNamed(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		var peeked int64
+		s = s.PeekNamed("source", func(int) {
+			atomic.AddInt64(&peeked, 1)
+		}).Filter(func(v int) bool {
+			return v%2 == 0
+		}).PeekNamed("even", func(int) {})
+
+		if got, want := s.String(), "Stream[source:0 even:0]"; got != want {
+			t.Errorf("s.String() is %q, want %q", got, want)
+		}
+
+		count := s.Count()
+		if count != 50 {
+			t.Errorf("count is %d, want 50", count)
+		}
+		if peeked != 100 {
+			t.Errorf("peeked is %d, want 100", peeked)
+		}
+		if got, want := s.String(), "Stream[source:100 even:50]"; got != want {
+			t.Errorf("s.String() is %q, want %q", got, want)
+		}
+	}
+
+	if got, want := Of(1).String(), "Stream[]"; got != want {
+		t.Errorf("Of(1).String() is %q, want %q", got, want)
+	}
+}
+
+func TestStream_Limit(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		limit    int
+	}{
+		{dataSize: 0, limit: 1},
+		{dataSize: 1, limit: 1},
+		{dataSize: 1000, limit: 100},
+	} {
+		var data []int
+		var want []int
+
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, i)
+			if i < tc.limit {
+				want = append(want, i)
+			}
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.Limit(tc.limit).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+
+	// the first elements are delayed, so that they arrive last.
+	delay := func(v int) {
+		if v < 5 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	t.Run("ordered parallel", func(t *testing.T) {
+		result := Range(0, 100).ParallelN(8).Peek(delay).Limit(5).ToSlice()
+		want := []int{0, 1, 2, 3, 4}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel filtered", func(t *testing.T) {
+		result := Range(0, 100).ParallelN(8).Peek(delay).Filter(func(v int) bool {
+			return v%2 == 1
+		}).Limit(3).ToSlice()
+		want := []int{1, 3, 5}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel infinite", func(t *testing.T) {
+		result := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(8), func(v int) int {
+			delay(v)
+			return v * 2
+		}).Limit(5).ToSlice()
+		want := []int{0, 2, 4, 6, 8}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel filtered infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(8).Filter(func(v int) bool {
+			return v%2 == 0
+		}).Limit(5).ToSlice()
+		want := []int{0, 2, 4, 6, 8}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel filtered channel-based", func(t *testing.T) {
+		// Parallel serves the parts of Range through channels, so the
+		// elements arrive out of encounter order.
+		result := Range(0, 100).ParallelN(8).Peek(delay).Parallel().Filter(
+			func(v int) bool {
+				return v%2 == 1
+			}).Limit(3).ToSlice()
+		want := []int{1, 3, 5}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel exhausted", func(t *testing.T) {
+		// Iterate, Limit and Concat are not fused, so the sources are
+		// channel-based, and they are exhausted while the goroutines of
+		// ParallelN wait for requests.
+		double := func(v int) int { return v * 2 }
+		result := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(200).ParallelN(4), double).Limit(300).ToSlice()
+		if want := Range(0, 400).StepBy(2).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+
+		result = Map(Concat(Of(1, 2, 3), Of(4, 5, 6)).ParallelN(4), double).Limit(10).ToSlice()
+		if want := []int{2, 4, 6, 8, 10, 12}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_Skip(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		skip     int
+	}{
+		{dataSize: 0, skip: 1},
+		{dataSize: 1, skip: 1},
+		{dataSize: 1000, skip: 100},
+	} {
+		var data []int
+		var want []int
+
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, i)
+			if i >= tc.skip {
+				want = append(want, i)
+			}
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.Skip(tc.skip).ToSlice()
+			if !slices.Equal(result, want) {
+				t.Errorf("result is %v, want %v", result, want)
+			}
+		}
+	}
+
+	// the first elements are delayed, so that they arrive last.
+	delay := func(v int) {
+		if v < 5 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	t.Run("ordered parallel", func(t *testing.T) {
+		result := Range(0, 10).ParallelN(8).Peek(delay).Skip(3).ToSlice()
+		want := []int{3, 4, 5, 6, 7, 8, 9}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel filtered", func(t *testing.T) {
+		result := Range(0, 10).ParallelN(8).Peek(delay).Filter(func(v int) bool {
+			return v%2 == 1
+		}).Skip(2).ToSlice()
+		want := []int{5, 7, 9}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(8).Peek(delay).Skip(3).Limit(4).ToSlice()
+		want := []int{3, 4, 5, 6}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel filtered infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(8).Filter(func(v int) bool {
+			return v%2 == 0
+		}).Skip(2).Limit(3).ToSlice()
+		want := []int{4, 6, 8}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("ordered parallel exhausted", func(t *testing.T) {
+		// Iterate, Limit and Concat are not fused, so the sources are
+		// channel-based, and they are exhausted while the goroutines of
+		// ParallelN wait for requests.
+		double := func(v int) int { return v * 2 }
+		result := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(200).ParallelN(4), double).Skip(7).ToSlice()
+		if want := Range(14, 400).StepBy(2).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+
+		result = Map(Concat(Of(1, 2, 3), Of(4, 5, 6)).ParallelN(4), double).Skip(1).ToSlice()
+		if want := []int{4, 6, 8, 10, 12}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("sequential then parallel", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).Skip(3).ParallelN(8).Peek(delay).Limit(4).ToSlice()
+		want := []int{3, 4, 5, 6}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_StepBy(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		n        int
+		want     []int
+	}{
+		{dataSize: 0, n: 2, want: nil},
+		{dataSize: 1, n: 2, want: []int{0}},
+		{dataSize: 5, n: 1, want: []int{0, 1, 2, 3, 4}},
+		{dataSize: 10, n: 3, want: []int{0, 3, 6, 9}},
+		{dataSize: 9, n: 3, want: []int{0, 3, 6}},
+	} {
+		result := Range(0, tc.dataSize).StepBy(tc.n).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("Range(0, %d).StepBy(%d) is %v, want %v",
+				tc.dataSize, tc.n, result, tc.want)
+		}
+	}
+
+	t.Run("parallel", func(t *testing.T) {
+		isEven := func(v int) bool { return v%2 == 0 }
+		want := Range(0, 200).Filter(isEven).ToSlice()
+
+		result := jittered(200).StepBy(2).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+
+		// the elements after Filter are selected in encounter order.
+		result = jittered(400).Filter(isEven).StepBy(2).ToSlice()
+		want = Range(0, 400).Filter(func(v int) bool {
+			return v%4 == 0
+		}).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+
+		// the orders of the selected elements are consecutive.
+		result = jittered(200).StepBy(2).Limit(3).ToSlice()
+		if want := []int{0, 2, 4}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_TakeWhile(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []int
+		want []int
+	}{
+		{data: nil, want: nil},
+		{data: []int{1, 2, 3}, want: []int{1, 2, 3}},
+		{data: []int{1, 2, 10, 3, 4}, want: []int{1, 2}},
+		{data: []int{10, 1, 2}, want: nil},
+	} {
+		result := Of(tc.data...).TakeWhile(func(v int) bool {
+			return v < 10
+		}).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("result is %v, want %v", result, tc.want)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		result := Iterate(1, func(v int) int {
+			return v * 2
+		}).TakeWhile(func(v int) bool {
+			return v < 1000
+		}).ToSlice()
+		want := []int{1, 2, 4, 8, 16, 32, 64, 128, 256, 512}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		result := jittered(200).TakeWhile(func(v int) bool {
+			return v < 100
+		}).ToSlice()
+		if want := Range(0, 100).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(4).Filter(func(v int) bool {
+			return v%2 == 0
+		}).TakeWhile(func(v int) bool {
+			return v < 10
+		}).ToSlice()
+		if want := []int{0, 2, 4, 6, 8}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel channel-based", func(t *testing.T) {
+		// Iterate and Limit are not fused, so the source is channel-based,
+		// and it is exhausted while the goroutines of ParallelN wait for
+		// requests.
+		result := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(200).ParallelN(4), func(v int) int {
+			return v * 10
+		}).TakeWhile(func(v int) bool {
+			return v < 1000
+		}).ToSlice()
+		if want := Range(0, 1000).StepBy(10).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
+
+func TestStream_DropWhile(t *testing.T) {
+	for _, tc := range [...]struct {
+		data []int
+		want []int
+	}{
+		{data: nil, want: nil},
+		{data: []int{1, 2, 3}, want: nil},
+		{data: []int{1, 2, 10, 3, 4}, want: []int{10, 3, 4}},
+		{data: []int{10, 1, 2}, want: []int{10, 1, 2}},
+	} {
+		result := Of(tc.data...).DropWhile(func(v int) bool {
+			return v < 10
+		}).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("result is %v, want %v", result, tc.want)
+		}
+	}
+
+	t.Run("parallel", func(t *testing.T) {
+		result := jittered(200).DropWhile(func(v int) bool {
+			return v < 100
+		}).ToSlice()
+		if want := Range(100, 200).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("parallel infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(4).Filter(func(v int) bool {
+			return v%2 == 0
+		}).DropWhile(func(v int) bool {
+			return v < 10
+		}).Limit(3).ToSlice()
+		if want := []int{10, 12, 14}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
 
-			if !slices.Equal(result, want) {
-				t.Errorf("result is %v, want %v", result, want)
-			}
+	t.Run("parallel channel-based", func(t *testing.T) {
+		// Iterate and Limit are not fused, so the source is channel-based,
+		// and it is exhausted while the goroutines of ParallelN wait for
+		// requests.
+		result := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(200).ParallelN(4), func(v int) int {
+			return v * 10
+		}).DropWhile(func(v int) bool {
+			return v < 1000
+		}).ToSlice()
+		if want := Range(1000, 2000).StepBy(10).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
 		}
-	}
+	})
 }
 
-func TestStream_Peek(t *testing.T) {
+func TestStream_Buffer(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
+		n        int
 	}{
-		{dataSize: 0},
-		{dataSize: 1},
-		{dataSize: 1000},
+		{dataSize: 0, n: 1},
+		{dataSize: 1, n: 1},
+		{dataSize: 1000, n: 1},
+		{dataSize: 1000, n: 100},
 	} {
 		var data []int
-		var want []int
-
 		for i := 0; i < tc.dataSize; i++ {
-			data = append(data, tc.dataSize-1-i)
-			want = append(want, i)
+			data = append(data, i)
 		}
 
-		t.Run("serial", func(t *testing.T) {
-			s := Of(data...)
+		result := Of(data...).Buffer(tc.n).ToSlice()
+		if !slices.Equal(result, data) {
+			t.Errorf("result is %v, want %v", result, data)
+		}
+	}
 
-			peekedChan := make(chan int, tc.dataSize)
-			peekFunc := func(t int) {
-				peekedChan <- t
-			}
+	t.Run("ahead", func(t *testing.T) {
+		var produced int64
 
-			result := s.Peek(peekFunc).Sorted(func(t1, t2 int) int {
-				return cmp.Compare(t1, t2)
-			}).ToSlice()
-			if !slices.Equal(result, want) {
-				t.Errorf("result is %v, want %v", result, want)
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).Peek(func(int) {
+			atomic.AddInt64(&produced, 1)
+		}).Buffer(10).Peek(func(v int) {
+			if v != 0 {
+				return
 			}
-
-			close(peekedChan)
-			var peeked []int
-			for v := range peekedChan {
-				peeked = append(peeked, v)
+			// The producer goes ahead while the first element is consumed.
+			deadline := time.Now().Add(10 * time.Second)
+			for atomic.LoadInt64(&produced) < 10 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
 			}
+		}).Limit(20).ToSlice()
 
-			if !slices.Equal(peeked, data) {
-				t.Errorf("peeked is %v, want %v", peeked, data)
-			}
-		})
+		if len(result) != 20 {
+			t.Errorf("len(result) is %d, want 20", len(result))
+		}
+		if atomic.LoadInt64(&produced) < 10 {
+			t.Errorf("produced is %d, want >= 10", produced)
+		}
+	})
+}
 
-		t.Run("parallel", func(t *testing.T) {
-			s := Of(data...).Parallel()
+func TestStream_Debounce(t *testing.T) {
+	// intervals before each element is produced
+	intervals := []time.Duration{
+		0,
+		time.Millisecond,
+		time.Millisecond,
+		500 * time.Millisecond,
+		time.Millisecond,
+		500 * time.Millisecond,
+	}
 
-			peekedChan := make(chan int, tc.dataSize)
-			peekFunc := func(t int) {
-				peekedChan <- t
+	result := Range(0, len(intervals)).Peek(func(v int) {
+		time.Sleep(intervals[v])
+	}).Debounce(100 * time.Millisecond).ToSlice()
+
+	want := []int{2, 4, 5}
+	if !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		result := Empty[int]().Debounce(time.Millisecond).ToSlice()
+		if len(result) != 0 {
+			t.Errorf("result is %v, want []", result)
+		}
+	})
+
+	t.Run("infinite", func(t *testing.T) {
+		// each element is produced after the previous one has been settled,
+		// so that no element is superseded.
+		settled := make(chan struct{}, 1)
+		done := make(chan struct{})
+		defer close(done)
+
+		result := Iterate(0, func(v int) int {
+			select {
+			case <-settled:
+			case <-done:
 			}
+			return v + 1
+		}).Debounce(time.Millisecond).Peek(func(int) {
+			settled <- struct{}{}
+		}).Limit(3).ToSlice()
+		want := []int{0, 1, 2}
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+}
 
-			result := s.Peek(peekFunc).Sorted(func(t1, t2 int) int {
-				return cmp.Compare(t1, t2)
-			}).ToSlice()
+func TestStream_WithTimeout(t *testing.T) {
+	t.Run("timed out", func(t *testing.T) {
+		// the source is blocked after the third element until the test ends.
+		blocked := make(chan struct{})
+		defer close(blocked)
 
-			if !slices.Equal(result, want) {
-				t.Errorf("result is %v, want %v", result, want)
+		s := Iterate(0, func(v int) int {
+			if v == 2 {
+				<-blocked
 			}
+			return v + 1
+		}).WithTimeout(200 * time.Millisecond)
 
-			close(peekedChan)
-			var peeked []int
-			for v := range peekedChan {
-				peeked = append(peeked, v)
+		result := s.ToSlice()
+		if want := []int{0, 1, 2}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+		if s.Err() != ErrTimeout {
+			t.Errorf("s.Err() is %v, want %v", s.Err(), ErrTimeout)
+		}
+	})
+
+	t.Run("downstream", func(t *testing.T) {
+		blocked := make(chan struct{})
+		defer close(blocked)
+
+		first := true
+		s := Generate(func() int {
+			if !first {
+				<-blocked
 			}
+			first = false
+			return 1
+		}).WithTimeout(50 * time.Millisecond).Filter(func(v int) bool {
+			return v > 0
+		}).Sorted(func(a, b int) int {
+			return a - b
+		})
 
-			if tc.dataSize != 0 && tc.dataSize != 1 && goMaxProcs != 1 {
-				if slices.Equal(peeked, data) {
-					t.Logf("tc.dataSize = %d", tc.dataSize)
-					t.Errorf("peeked is identical to data")
+		s.Count()
+		if s.Err() != ErrTimeout {
+			t.Errorf("s.Err() is %v, want %v", s.Err(), ErrTimeout)
+		}
+	})
+
+	for _, parallel := range []bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+		s = s.WithTimeout(time.Minute)
+
+		result := s.ToSlice()
+		slices.Sort(result)
+		if len(result) != 100 || result[0] != 0 || result[99] != 99 {
+			t.Errorf("[%v] result is %v, want [0..99]", parallel, result)
+		}
+		if s.Err() != nil {
+			t.Errorf("[%v] s.Err() is %v, want nil", parallel, s.Err())
+		}
+	}
+}
+
+func TestStream_ParallelN(t *testing.T) {
+	for _, n := range []int{1, 2, 64} {
+		var running, maxRunning int64
+		result := Range(0, 1000).ParallelN(n).Filter(func(v int) bool {
+			r := atomic.AddInt64(&running, 1)
+			for {
+				m := atomic.LoadInt64(&maxRunning)
+				if r <= m || atomic.CompareAndSwapInt64(&maxRunning, m, r) {
+					break
 				}
 			}
+			time.Sleep(10 * time.Microsecond)
+			atomic.AddInt64(&running, -1)
+			return v%2 == 0
+		}).ToSlice()
 
-			sort.Slice(peeked, func(i, j int) bool {
-				return peeked[i] < peeked[j]
-			})
-			if !slices.Equal(peeked, want) {
-				t.Errorf("peeked is %v, want %v", peeked, want)
-			}
-		})
+		if len(result) != 500 {
+			t.Errorf("[%d] len(result) is %d, want 500", n, len(result))
+		}
+		if maxRunning > int64(n) {
+			t.Errorf("[%d] maxRunning is %d, want <= %d", n, maxRunning, n)
+		}
 	}
+
+	s := Range(0, 10).ParallelN(4)
+	if s.ParallelN(4) != s {
+		t.Errorf("ParallelN(4) of the same parallelism doesn't return itself")
+	}
+	if count := s.ParallelN(2).Count(); count != 10 {
+		t.Errorf("count is %d, want 10", count)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("ParallelN(0) doesn't panic")
+		}
+	}()
+	Range(0, 10).ParallelN(0)
 }
 
-func TestStream_Limit(t *testing.T) {
-	for _, tc := range [...]struct {
-		dataSize int
-		limit    int
-	}{
-		{dataSize: 0, limit: 1},
-		{dataSize: 1, limit: 1},
-		{dataSize: 1000, limit: 100},
-	} {
-		var data []int
-		var want []int
+func TestStream_Unordered(t *testing.T) {
+	want := make([]int, 1000)
+	for i := range want {
+		want[i] = i
+	}
 
-		for i := 0; i < tc.dataSize; i++ {
-			data = append(data, i)
-			if i < tc.limit {
-				want = append(want, i)
-			}
+	for _, parallel := range []bool{false, true} {
+		s := Of(want...)
+		if parallel {
+			s = s.Parallel()
 		}
 
-		for _, parallel := range [...]bool{false, true} {
-			s := Of(data...)
-			if parallel {
-				s = s.Parallel()
-			}
+		result := s.Unordered().Filter(func(v int) bool {
+			return v >= 0
+		}).ToSlice()
+		if !parallel && !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
 
-			result := s.Limit(tc.limit).ToSlice()
-			if !slices.Equal(result, want) {
-				t.Errorf("result is %v, want %v", result, want)
-			}
+		sort.Ints(result)
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] sorted result is %v, want %v", parallel, result, want)
 		}
 	}
+
+	s := Of(1, 2, 3).Unordered()
+	if s.Unordered() != s {
+		t.Errorf("Unordered() of unordered stream doesn't return itself")
+	}
+	s.Count()
 }
 
-func TestStream_Skip(t *testing.T) {
-	for _, tc := range [...]struct {
-		dataSize int
-		skip     int
-	}{
-		{dataSize: 0, skip: 1},
-		{dataSize: 1, skip: 1},
-		{dataSize: 1000, skip: 100},
-	} {
-		var data []int
-		var want []int
+func TestStream_Sequential(t *testing.T) {
+	want := make([]int, 1000)
+	for i := range want {
+		want[i] = i * 2
+	}
 
-		for i := 0; i < tc.dataSize; i++ {
-			data = append(data, i)
-			if i >= tc.skip {
-				want = append(want, i)
-			}
+	var result []int
+	Map(Range(0, 1000).Parallel(), func(v int) int {
+		return v * 2
+	}).Sequential().ForEach(func(v int) {
+		result = append(result, v)
+	})
+	if !slices.Equal(result, want) {
+		t.Errorf("result is %v, want %v", result, want)
+	}
+
+	t.Run("unordered", func(t *testing.T) {
+		var count int
+		Iterate(0, func(v int) int {
+			return v + 1
+		}).Parallel().Unordered().Sequential().Limit(100).ForEach(func(v int) {
+			count++
+		})
+		if count != 100 {
+			t.Errorf("count is %d, want 100", count)
+		}
+	})
+
+	t.Run("reordered", func(t *testing.T) {
+		result := jittered(200).Sequential().ToSlice()
+		if want := Range(0, 200).ToSlice(); !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
 		}
+	})
 
-		for _, parallel := range [...]bool{false, true} {
+	t.Run("infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).Parallel().Sequential().Limit(3).ToSlice()
+		if want := []int{0, 1, 2}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("channel-based", func(t *testing.T) {
+		// Iterate and Limit are not fused, so the source is channel-based,
+		// and it is exhausted while the goroutines of Parallel wait for
+		// requests.
+		s := Map(Iterate(0, func(v int) int {
+			return v + 1
+		}).Limit(200).ParallelN(4), func(v int) int {
+			return v * 2
+		})
+		if count := s.Sequential().Count(); count != 200 {
+			t.Errorf("count is %d, want 200", count)
+		}
+	})
+
+	t.Run("sparse infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(4).Filter(func(v int) bool {
+			return v%2 == 0
+		}).Sequential().Limit(3).ToSlice()
+		if want := []int{0, 2, 4}; !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	t.Run("sparse", func(t *testing.T) {
+		result := jittered(200).Filter(func(v int) bool {
+			return v%3 == 0
+		}).Sequential().ToSlice()
+		want := Range(0, 200).Filter(func(v int) bool {
+			return v%3 == 0
+		}).ToSlice()
+		if !slices.Equal(result, want) {
+			t.Errorf("result is %v, want %v", result, want)
+		}
+	})
+
+	s := Of(1, 2, 3)
+	if s.Sequential() != s {
+		t.Errorf("Sequential() of sequential stream doesn't return itself")
+	}
+	s.Count()
+}
+
+// jittered returns a parallel stream of 0, 1, ..., n-1 whose elements are
+// delayed randomly, so that they arrive out of encounter order.
+func jittered(n int) Stream[int] {
+	return Map(Range(0, n).ParallelN(8), func(v int) int {
+		time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+		return v
+	})
+}
+
+func TestStream_ToSortedSlice(t *testing.T) {
+	type pair struct {
+		key   int
+		order int
+	}
+
+	var data []pair
+	for i := 0; i < 1000; i++ {
+		data = append(data, pair{key: (i * 7919) % 10, order: i})
+	}
+
+	want := slices.Clone(data)
+	slices.SortStableFunc(want, func(a, b pair) int {
+		return a.key - b.key
+	})
+
+	for _, parallel := range [...]bool{false, true} {
+		for _, n := range []int{1, 3, 8} {
 			s := Of(data...)
 			if parallel {
-				s = s.Parallel()
+				s = s.ParallelN(n)
 			}
 
-			result := s.Skip(tc.skip).ToSlice()
+			result := s.ToSortedSlice(func(a, b pair) int {
+				return a.key - b.key
+			})
 			if !slices.Equal(result, want) {
-				t.Errorf("result is %v, want %v", result, want)
+				t.Errorf("[%v:%d] result is %v, want %v", parallel, n, result, want)
 			}
 		}
 	}
+
+	if result := Empty[int]().Parallel().ToSortedSlice(cmp.Compare[int]); len(result) != 0 {
+		t.Errorf("result is %v, want []", result)
+	}
 }
 
 func TestStream_ToSlice(t *testing.T) {
@@ -501,6 +1986,29 @@ func TestStream_Count(t *testing.T) {
 	}
 }
 
+func TestStream_Tally(t *testing.T) {
+	for _, parallel := range [...]bool{false, true} {
+		s := Range(0, 100)
+		if parallel {
+			s = s.Parallel()
+		}
+
+		result := s.Tally(
+			func(v int) bool { return v%2 == 0 },
+			func(v int) bool { return v%3 == 0 },
+			func(v int) bool { return v < 0 },
+		)
+		want := []int{50, 34, 0}
+		if !slices.Equal(result, want) {
+			t.Errorf("[%v] result is %v, want %v", parallel, result, want)
+		}
+	}
+
+	if result := Of(1, 2, 3).Tally(); len(result) != 0 {
+		t.Errorf("result is %v, want []", result)
+	}
+}
+
 func TestStream_AnyMatch(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize  int
@@ -612,6 +2120,128 @@ func TestStream_NoneMatch(t *testing.T) {
 	}
 }
 
+func TestStream_Single(t *testing.T) {
+	for _, tc := range [...]struct {
+		dataSize int
+		err      error
+	}{
+		{dataSize: 0, err: ErrNoElements},
+		{dataSize: 1, err: nil},
+		{dataSize: 2, err: ErrMultipleElements},
+		{dataSize: 1000, err: ErrMultipleElements},
+	} {
+		var data []int
+
+		for i := 0; i < tc.dataSize; i++ {
+			data = append(data, 777+i)
+		}
+
+		for _, parallel := range [...]bool{false, true} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			single, err := s.Single()
+			if err != tc.err {
+				t.Errorf("err is %v, want %v", err, tc.err)
+			}
+			if tc.err != nil {
+				if single.IsPresent() {
+					t.Errorf("single.IsPresent() is true, want false")
+				}
+				continue
+			}
+
+			if single.Get() != 777 {
+				t.Errorf("single.Get() is %d, want 777", single.Get())
+			}
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		_, err := Iterate(0, func(v int) int {
+			return v + 1
+		}).Single()
+		if err != ErrMultipleElements {
+			t.Errorf("err is %v, want %v", err, ErrMultipleElements)
+		}
+	})
+}
+
+func TestStream_ElementAt(t *testing.T) {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i * 10
+	}
+
+	for _, parallel := range [...]bool{false, true} {
+		for _, n := range []int{0, 1, 50, 99} {
+			s := Of(data...)
+			if parallel {
+				s = s.Parallel()
+			}
+
+			result := s.ElementAt(n)
+			if !result.IsPresent() || result.Get() != n*10 {
+				t.Errorf("[%v] ElementAt(%d) is %v, want %d",
+					parallel, n, result, n*10)
+			}
+		}
+
+		s := Of(data...)
+		if parallel {
+			s = s.Parallel()
+		}
+		if result := s.ElementAt(100); result.IsPresent() {
+			t.Errorf("[%v] ElementAt(100) is %v, want empty", parallel, result)
+		}
+	}
+
+	t.Run("infinite", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).Parallel().ElementAt(1000)
+		if result.Get() != 1000 {
+			t.Errorf("result.Get() is %d, want 1000", result.Get())
+		}
+	})
+
+	t.Run("infinite filtered", func(t *testing.T) {
+		result := Iterate(0, func(v int) int {
+			return v + 1
+		}).ParallelN(4).Filter(func(v int) bool {
+			return v%2 == 0
+		}).ElementAt(3)
+		if !result.IsPresent() || result.Get() != 6 {
+			t.Errorf("result is %v, want 6", result)
+		}
+	})
+
+	t.Run("reordered", func(t *testing.T) {
+		// Parallel serves the parts of Range through channels, and the first
+		// elements are delayed, so that they arrive last.
+		delayed := func() Stream[int] {
+			return Range(0, 100).ParallelN(4).Peek(func(v int) {
+				if v < 5 {
+					time.Sleep(20 * time.Millisecond)
+				}
+			}).Parallel()
+		}
+
+		if result := delayed().ElementAt(2); !result.IsPresent() || result.Get() != 2 {
+			t.Errorf("result is %v, want 2", result)
+		}
+
+		result := delayed().Filter(func(v int) bool {
+			return v%2 == 1
+		}).ElementAt(2)
+		if !result.IsPresent() || result.Get() != 5 {
+			t.Errorf("filtered result is %v, want 5", result)
+		}
+	})
+}
+
 func TestStream_FindFirst(t *testing.T) {
 	for _, tc := range [...]struct {
 		dataSize int
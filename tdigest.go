@@ -0,0 +1,132 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"math"
+	"slices"
+)
+
+// tDigestCompression bounds the number of centroids held by a TDigest.
+// Larger values give more accurate quantiles at the cost of memory.
+const tDigestCompression = 100
+
+// centroid is a cluster of values summarized by their mean and weight.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest is the intermediate accumulation type of PercentileCollector,
+// which is a streaming quantile sketch (t-digest by Ted Dunning). Values are
+// clustered into centroids, which are small near both tails and large around
+// the median, so extreme quantiles are estimated accurately with a bounded
+// number of centroids. Two TDigests can be merged, which makes it suitable
+// for parallel streams.
+type TDigest struct {
+	centroids []centroid // sorted by mean
+	unmerged  []centroid
+	count     float64
+	min       float64
+	max       float64
+}
+
+func newTDigest() *TDigest {
+	return &TDigest{
+		min: math.Inf(1),
+		max: math.Inf(-1),
+	}
+}
+
+// add adds a value to the digest.
+func (td *TDigest) add(value float64) {
+	td.unmerged = append(td.unmerged, centroid{mean: value, weight: 1})
+	td.count++
+	td.min = min(td.min, value)
+	td.max = max(td.max, value)
+
+	if len(td.unmerged) >= 5*tDigestCompression {
+		td.compress()
+	}
+}
+
+// merge merges other into td.
+func (td *TDigest) merge(other *TDigest) {
+	td.unmerged = append(td.unmerged, other.centroids...)
+	td.unmerged = append(td.unmerged, other.unmerged...)
+	td.count += other.count
+	td.min = min(td.min, other.min)
+	td.max = max(td.max, other.max)
+	td.compress()
+}
+
+// compress merges unmerged centroids into the centroids, keeping the weight
+// of each centroid within the size bound 4*count*q*(1-q)/compression.
+func (td *TDigest) compress() {
+	if len(td.unmerged) == 0 {
+		return
+	}
+
+	all := append(td.centroids, td.unmerged...)
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	merged := make([]centroid, 0, len(all))
+	cumulative := 0.0
+	current := all[0]
+	for _, c := range all[1:] {
+		weight := current.weight + c.weight
+		q := (cumulative + weight/2) / td.count
+		if weight <= max(1, 4*td.count*q*(1-q)/tDigestCompression) {
+			current.mean += (c.mean - current.mean) * c.weight / weight
+			current.weight = weight
+			continue
+		}
+		merged = append(merged, current)
+		cumulative += current.weight
+		current = c
+	}
+	td.centroids = append(merged, current)
+	td.unmerged = nil
+}
+
+// quantile returns the estimated value at quantile q in [0, 1]. If the
+// digest is empty, quantile returns NaN.
+func (td *TDigest) quantile(q float64) float64 {
+	td.compress()
+
+	if len(td.centroids) == 0 {
+		return math.NaN()
+	}
+
+	index := q * td.count
+	first := td.centroids[0]
+	if index < first.weight/2 {
+		return td.min + (first.mean-td.min)*index/(first.weight/2)
+	}
+
+	// center is the cumulative weight at the center of the centroid.
+	center := first.weight / 2
+	for i := 1; i < len(td.centroids); i++ {
+		prev, next := td.centroids[i-1], td.centroids[i]
+		nextCenter := center + (prev.weight+next.weight)/2
+		if index < nextCenter {
+			fraction := (index - center) / (nextCenter - center)
+			return prev.mean + (next.mean-prev.mean)*fraction
+		}
+		center = nextCenter
+	}
+
+	last := td.centroids[len(td.centroids)-1]
+	if index >= td.count {
+		return td.max
+	}
+	return last.mean + (td.max-last.mean)*(index-center)/(td.count-center)
+}
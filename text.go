@@ -0,0 +1,36 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"bufio"
+	"strings"
+)
+
+// RunesOf returns a sequential ordered Stream of the runes of s.
+func RunesOf(s string) Stream[rune] {
+	return Of([]rune(s)...)
+}
+
+// WordsOf returns a sequential ordered Stream of the space-separated words
+// of s. Spaces are defined by unicode.IsSpace, as bufio.ScanWords does.
+func WordsOf(s string) Stream[string] {
+	return stringTokens(s, bufio.ScanWords)
+}
+
+// LinesOf returns a sequential ordered Stream of the lines of s, stripped of
+// any trailing end-of-line marker, as FileLines does for a file.
+func LinesOf(s string) Stream[string] {
+	return stringTokens(s, bufio.ScanLines)
+}
+
+// stringTokens returns a sequential ordered Stream of the tokens of s
+// split by the split function.
+func stringTokens(s string, split bufio.SplitFunc) Stream[string] {
+	input := bufio.NewScanner(strings.NewReader(s))
+	// A token may be as long as s itself.
+	input.Buffer(nil, max(len(s)+1, bufio.MaxScanTokenSize))
+	input.Split(split)
+
+	return scannerStream(input, func() {})
+}
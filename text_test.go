@@ -0,0 +1,55 @@
+// Copyright © 2026 Yoshiki Shibata. All rights reserved.
+
+package gostream
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestText_RunesOf(t *testing.T) {
+	for _, s := range [...]string{"", "a", "Hello, 世界"} {
+		result := RunesOf(s).ToSlice()
+		if string(result) != s {
+			t.Errorf("string(result) is %q, want %q", string(result), s)
+		}
+	}
+}
+
+func TestText_WordsOf(t *testing.T) {
+	for _, tc := range [...]struct {
+		s    string
+		want []string
+	}{
+		{s: "", want: nil},
+		{s: "  \n ", want: nil},
+		{s: "Alice was  beginning\tto get\nvery tired",
+			want: []string{"Alice", "was", "beginning", "to", "get", "very", "tired"}},
+	} {
+		result := WordsOf(tc.s).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("WordsOf(%q) is %q, want %q", tc.s, result, tc.want)
+		}
+	}
+}
+
+func TestText_LinesOf(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+
+	for _, tc := range [...]struct {
+		s    string
+		want []string
+	}{
+		{s: "", want: nil},
+		{s: "a", want: []string{"a"}},
+		{s: "a\n", want: []string{"a"}},
+		{s: "a\r\n\nb", want: []string{"a", "", "b"}},
+		{s: long + "\n" + long, want: []string{long, long}},
+	} {
+		result := LinesOf(tc.s).ToSlice()
+		if !slices.Equal(result, tc.want) {
+			t.Errorf("LinesOf(%.20q) is %.40q, want %.40q", tc.s, result, tc.want)
+		}
+	}
+}